@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"log"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/you/swarmone/internal/httpapi"
 	"github.com/you/swarmone/internal/orch"
+	"github.com/you/swarmone/internal/provider"
 )
 
 func main() {
@@ -17,12 +21,45 @@ func main() {
 		"./backend/.env",
 	)
 
+	registerTools()
+
 	cfg, keys, err := orch.Load()
 	if err != nil {
 		log.Fatalf("load config: %v", err)
 	}
+	if f := cfg.Consensus.File; f != "" {
+		log.Printf("consensus mode %q read from %s", cfg.Consensus.Mode, f)
+	} else {
+		log.Printf("no config file found (set SWARMONE_CONFIG); consensus mode %q", cfg.Consensus.Mode)
+	}
 
 	if err := httpapi.Serve(cfg, keys); err != nil {
 		log.Fatalf("serve: %v", err)
 	}
 }
+
+// registerTools installs the built-in tools runners can opt into via "tools".
+func registerTools() {
+	err := orch.RegisterTool(provider.Tool{
+		Name:        "current_time",
+		Description: "Returns the current date and time in RFC 3339 format (UTC unless a timezone is given).",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"timezone":{"type":"string","description":"IANA name, e.g. Europe/Berlin"}}}`),
+	}, func(_ context.Context, args json.RawMessage) (string, error) {
+		var in struct {
+			Timezone string `json:"timezone"`
+		}
+		_ = json.Unmarshal(args, &in)
+		loc := time.UTC
+		if in.Timezone != "" {
+			l, err := time.LoadLocation(in.Timezone)
+			if err != nil {
+				return "", err
+			}
+			loc = l
+		}
+		return time.Now().In(loc).Format(time.RFC3339), nil
+	})
+	if err != nil {
+		log.Fatalf("register tools: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package httpapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/swarmone/internal/orch"
+	"github.com/you/swarmone/internal/provider"
+)
+
+const (
+	maxAttachments     = 10
+	maxAttachmentBytes = 20 << 20
+)
+
+// attachmentReq is a base64 attachment in a JSON ask body. Data may also be a
+// data URL ("data:image/png;base64,..."), whose type then fills MIMEType.
+type attachmentReq struct {
+	Name     string `json:"name"`
+	MIMEType string `json:"mime_type"`
+	Data     string `json:"data"`
+}
+
+func (a attachmentReq) decode(i int) (provider.Attachment, error) {
+	name := a.Name
+	if name == "" {
+		name = fmt.Sprintf("attachment %d", i)
+	}
+	data, mt := a.Data, a.MIMEType
+	if rest, ok := strings.CutPrefix(data, "data:"); ok {
+		meta, payload, found := strings.Cut(rest, ",")
+		if !found || !strings.HasSuffix(meta, ";base64") {
+			return provider.Attachment{}, fmt.Errorf("%s: only base64 data URLs are supported", name)
+		}
+		if mt == "" {
+			mt = strings.TrimSuffix(meta, ";base64")
+		}
+		data = payload
+	}
+	data = strings.TrimSpace(data)
+	if base64.StdEncoding.DecodedLen(len(data)) > maxAttachmentBytes+2 {
+		return provider.Attachment{}, fmt.Errorf("%s: larger than %d bytes", name, maxAttachmentBytes)
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return provider.Attachment{}, fmt.Errorf("%s: invalid base64: %v", name, err)
+	}
+	return provider.NewAttachment(name, mt, raw)
+}
+
+// bindAsk reads an ask request from either a JSON body or a multipart form.
+// The form has the fields "instruction" and optionally "response_format"
+// (JSON), plus any number of files under "files" (or "file").
+func bindAsk(c *gin.Context) (orch.Query, error) {
+	if c.ContentType() != "multipart/form-data" {
+		var req askReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			return orch.Query{}, err
+		}
+		return req.query()
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return orch.Query{}, err
+	}
+	req := askReq{Instruction: strings.TrimSpace(c.PostForm("instruction")), Context: c.PostForm("context")}
+	if req.Instruction == "" {
+		return orch.Query{}, errors.New("instruction is required")
+	}
+	if rf := c.PostForm("response_format"); rf != "" {
+		if err := json.Unmarshal([]byte(rf), &req.ResponseFormat); err != nil {
+			return orch.Query{}, fmt.Errorf("response_format: %v", err)
+		}
+	}
+	q, err := req.query()
+	if err != nil {
+		return q, err
+	}
+	files := append(form.File["files"], form.File["file"]...)
+	if len(files) > maxAttachments {
+		return q, fmt.Errorf("at most %d attachments allowed", maxAttachments)
+	}
+	for _, fh := range files {
+		if fh.Size > maxAttachmentBytes {
+			return q, fmt.Errorf("%s: larger than %d bytes", fh.Filename, maxAttachmentBytes)
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return q, err
+		}
+		raw, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return q, err
+		}
+		att, err := provider.NewAttachment(fh.Filename, fh.Header.Get("Content-Type"), raw)
+		if err != nil {
+			return q, err
+		}
+		q.Attachments = append(q.Attachments, att)
+	}
+	return q, nil
+}
@@ -0,0 +1,214 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/swarmone/internal/orch"
+)
+
+var (
+	testPNG = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 32)...)
+	testPDF = []byte("%PDF-1.4\n1 0 obj <<>> endobj\n%%EOF\n")
+)
+
+type formFile struct {
+	name string
+	data []byte
+}
+
+// multipartBody builds an ask form; files go under "files".
+func multipartBody(t *testing.T, instruction string, files ...formFile) (io.Reader, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	if instruction != "" {
+		_ = w.WriteField("instruction", instruction)
+	}
+	for _, f := range files {
+		fw, err := w.CreateFormFile("files", f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(f.data)
+	}
+	w.Close()
+	return &buf, w.FormDataContentType()
+}
+
+// jsonAsk builds a JSON ask body with the given attachments.
+func jsonAsk(atts ...attachmentReq) (io.Reader, string) {
+	b, _ := json.Marshal(askReq{Instruction: "Describe it.", Attachments: atts})
+	return bytes.NewReader(b), "application/json"
+}
+
+func TestBindAskAttachments(t *testing.T) {
+	b64 := base64.StdEncoding.EncodeToString
+	eleven := make([]attachmentReq, 11)
+	elevenFiles := make([]formFile, 11)
+	for i := range eleven {
+		eleven[i] = attachmentReq{Data: b64(testPNG)}
+		elevenFiles[i] = formFile{fmt.Sprintf("%d.png", i), testPNG}
+	}
+	// Decodes to just over the limit; never decoded, so it needn't be valid.
+	huge := strings.Repeat("A", (maxAttachmentBytes/3+2)*4)
+
+	tests := []struct {
+		name      string
+		body      func(t *testing.T) (io.Reader, string)
+		wantNames []string
+		wantTypes []string
+		wantErr   string
+	}{
+		{
+			name: "base64 with a MIME type",
+			body: func(*testing.T) (io.Reader, string) {
+				return jsonAsk(attachmentReq{Name: "a.png", MIMEType: "image/png", Data: b64(testPNG)})
+			},
+			wantNames: []string{"a.png"}, wantTypes: []string{"image/png"},
+		},
+		{
+			name: "data URL fills the MIME type",
+			body: func(*testing.T) (io.Reader, string) {
+				return jsonAsk(attachmentReq{Data: "data:application/pdf;base64," + b64(testPDF)})
+			},
+			wantNames: []string{"attachment 0"}, wantTypes: []string{"application/pdf"},
+		},
+		{
+			name: "type is sniffed and whitespace trimmed",
+			body: func(*testing.T) (io.Reader, string) {
+				return jsonAsk(attachmentReq{Name: "x", Data: "\n " + b64(testPNG) + " \n"})
+			},
+			wantNames: []string{"x"}, wantTypes: []string{"image/png"},
+		},
+		{
+			name:    "non-base64 data URL",
+			body:    func(*testing.T) (io.Reader, string) { return jsonAsk(attachmentReq{Data: "data:image/png,abc"}) },
+			wantErr: "only base64 data URLs",
+		},
+		{
+			name:    "invalid base64",
+			body:    func(*testing.T) (io.Reader, string) { return jsonAsk(attachmentReq{Data: "not base64!"}) },
+			wantErr: "invalid base64",
+		},
+		{
+			name:    "unsupported type",
+			body:    func(*testing.T) (io.Reader, string) { return jsonAsk(attachmentReq{Data: b64([]byte("plain text"))}) },
+			wantErr: "unsupported type",
+		},
+		{
+			name:    "more than 10 in JSON",
+			body:    func(*testing.T) (io.Reader, string) { return jsonAsk(eleven...) },
+			wantErr: "at most 10 attachments",
+		},
+		{
+			name:    "over 20 MB in JSON",
+			body:    func(*testing.T) (io.Reader, string) { return jsonAsk(attachmentReq{Name: "big", Data: huge}) },
+			wantErr: "big: larger than",
+		},
+		{
+			name: "multipart files",
+			body: func(t *testing.T) (io.Reader, string) {
+				return multipartBody(t, "Compare them.", formFile{"a.png", testPNG}, formFile{"b.pdf", testPDF})
+			},
+			wantNames: []string{"a.png", "b.pdf"}, wantTypes: []string{"image/png", "application/pdf"},
+		},
+		{
+			name:    "more than 10 in a form",
+			body:    func(t *testing.T) (io.Reader, string) { return multipartBody(t, "Compare them.", elevenFiles...) },
+			wantErr: "at most 10 attachments",
+		},
+		{
+			name: "over 20 MB in a form",
+			body: func(t *testing.T) (io.Reader, string) {
+				return multipartBody(t, "Look.", formFile{"big.png", append(testPNG, make([]byte, maxAttachmentBytes)...)})
+			},
+			wantErr: "big.png: larger than",
+		},
+		{
+			name:    "form without an instruction",
+			body:    func(t *testing.T) (io.Reader, string) { return multipartBody(t, "", formFile{"a.png", testPNG}) },
+			wantErr: "instruction is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, ct := tt.body(t)
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/v1/ask", body)
+			c.Request.Header.Set("Content-Type", ct)
+
+			q, err := bindAsk(c)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("bindAsk error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("bindAsk: %v", err)
+			}
+			if len(q.Attachments) != len(tt.wantNames) {
+				t.Fatalf("got %d attachments, want %d", len(q.Attachments), len(tt.wantNames))
+			}
+			for i, a := range q.Attachments {
+				if a.Name != tt.wantNames[i] || a.MIMEType != tt.wantTypes[i] || len(a.Data) == 0 {
+					t.Errorf("attachment %d = %s %s (%d bytes), want %s %s", i, a.Name, a.MIMEType, len(a.Data), tt.wantNames[i], tt.wantTypes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAskSkipsRunnersWithoutModality(t *testing.T) {
+	// openai-compatible takes images only; it is never reached here.
+	imageOnly := orch.RunnerSpec{
+		Name: "vision", Provider: "openai-compatible", Model: "api-vision", MaxTokens: 64,
+		BaseURL: "http://127.0.0.1:1/v1", Retry: &orch.RetrySpec{MaxAttempts: 1},
+	}
+	tests := []struct {
+		name       string
+		runners    []orch.RunnerSpec
+		wantStatus int
+		wantKinds  []string // runner_errors kinds; "" = answered
+	}{
+		{name: "capable runner answers, the other is skipped", runners: []orch.RunnerSpec{mockRunner("api-a"), imageOnly}, wantStatus: http.StatusOK, wantKinds: []string{"", "unsupported_input"}},
+		{name: "no capable runner", runners: []orch.RunnerSpec{imageOnly}, wantStatus: http.StatusInternalServerError, wantKinds: []string{"unsupported_input"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, tt.runners...)
+			body, ct := multipartBody(t, "Summarize the document.", formFile{"doc.pdf", testPDF})
+			resp, err := http.Post(srv.URL+"/v1/ask", ct, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			var out struct {
+				RunnerErrors []*orch.RunnerError `json:"runner_errors"`
+			}
+			raw, _ := io.ReadAll(resp.Body)
+			if err := json.Unmarshal(raw, &out); err != nil || resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status %d, body %s; want %d", resp.StatusCode, raw, tt.wantStatus)
+			}
+			for i, want := range tt.wantKinds {
+				got := ""
+				if e := out.RunnerErrors[i]; e != nil {
+					got = e.Kind
+				}
+				if got != want {
+					t.Errorf("runner %d error kind = %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/swarmone/internal/orch"
+	"github.com/you/swarmone/internal/provider"
+)
+
+// maxEmbedInputs caps the texts of one /v1/embeddings request.
+const maxEmbedInputs = 256
+
+// embedReq mirrors OpenAI's embeddings request: input is a string or an
+// array of strings.
+type embedReq struct {
+	Input json.RawMessage `json:"input" binding:"required"`
+}
+
+func (r embedReq) texts() ([]string, error) {
+	var texts []string
+	var one string
+	if err := json.Unmarshal(r.Input, &one); err == nil {
+		texts = []string{one}
+	} else if err := json.Unmarshal(r.Input, &texts); err != nil {
+		return nil, errors.New("input must be a string or an array of strings")
+	}
+	if len(texts) == 0 {
+		return nil, errors.New("input is empty")
+	}
+	if len(texts) > maxEmbedInputs {
+		return nil, fmt.Errorf("at most %d inputs allowed", maxEmbedInputs)
+	}
+	for i, t := range texts {
+		if strings.TrimSpace(t) == "" {
+			return nil, fmt.Errorf("input %d is empty", i)
+		}
+	}
+	return texts, nil
+}
+
+// embeddings returns one vector per input, in OpenAI's response shape.
+func (s *Server) embeddings(c *gin.Context) {
+	var req embedReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+	texts, err := req.texts()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+
+	out, err := orch.Embed(c.Request.Context(), s.Cfg, s.Keys, texts)
+	if err != nil {
+		kind := orch.ErrorKind(err)
+		c.JSON(embedStatus(kind), gin.H{"detail": err.Error(), "kind": kind})
+		return
+	}
+	data := make([]gin.H, 0, len(out.Vectors))
+	for i, v := range out.Vectors {
+		data = append(data, gin.H{"object": "embedding", "index": i, "embedding": v})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"object":   "list",
+		"data":     data,
+		"provider": s.Cfg.Embeddings.Provider,
+		"model":    s.Cfg.Embeddings.Model,
+		"usage":    out.Usage,
+		"attempts": out.Attempts,
+	})
+}
+
+// embedStatus maps the kind of an Embed failure to an HTTP status: local
+// rate limits and open circuits are the caller's cue to back off, rejected
+// input is the caller's fault, anything else is ours.
+func embedStatus(kind string) int {
+	switch kind {
+	case orch.KindRateLimited:
+		return http.StatusTooManyRequests
+	case orch.KindCircuitOpen:
+		return http.StatusServiceUnavailable
+	case string(provider.KindInvalidRequest):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,111 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/you/swarmone/internal/orch"
+)
+
+func TestEmbeddings(t *testing.T) {
+	// upstream is an OpenAI-compatible embeddings server that fails by model.
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		switch req.Model {
+		case "api-embed-down":
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, `{"error":{"message":"backend crashed","type":"server_error"}}`)
+		case "api-embed-reject":
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error":{"message":"this model does not support embeddings","type":"invalid_request_error"}}`)
+		}
+	}))
+	defer upstream.Close()
+	compat := func(model string) orch.RunnerSpec {
+		return orch.RunnerSpec{Provider: "openai-compatible", Model: model, BaseURL: upstream.URL, Retry: &orch.RetrySpec{MaxAttempts: 1}}
+	}
+	mock := func(model string) orch.RunnerSpec { return orch.RunnerSpec{Provider: "mock", Model: model} }
+
+	tests := []struct {
+		name         string
+		embeddings   orch.RunnerSpec
+		body         string
+		wantStatuses []int // one request per status
+		wantKind     string
+		wantDetail   string
+		wantVectors  int
+	}{
+		{name: "array input", embeddings: mock("api-embed"), body: `{"input":["red apple","green apple"]}`, wantStatuses: []int{200}, wantVectors: 2},
+		{name: "string input", embeddings: mock("api-embed"), body: `{"input":"red apple"}`, wantStatuses: []int{200}, wantVectors: 1},
+		{name: "missing input", embeddings: mock("api-embed"), body: `{}`, wantStatuses: []int{400}, wantDetail: "Input"},
+		{name: "input of the wrong type", embeddings: mock("api-embed"), body: `{"input":3}`, wantStatuses: []int{400}, wantDetail: "string or an array"},
+		{name: "blank input", embeddings: mock("api-embed"), body: `{"input":["a","  "]}`, wantStatuses: []int{400}, wantDetail: "input 1 is empty"},
+		{
+			name: "local rate limit is 429", embeddings: mock("api-embed-limited"), body: `{"input":"a"}`,
+			wantStatuses: []int{200, 429}, wantKind: orch.KindRateLimited,
+		},
+		{
+			name: "open circuit is 503", embeddings: compat("api-embed-down"), body: `{"input":"a"}`,
+			wantStatuses: []int{500, 503}, wantKind: orch.KindCircuitOpen,
+		},
+		{
+			name: "input rejected by the provider is 400", embeddings: compat("api-embed-reject"), body: `{"input":"a"}`,
+			wantStatuses: []int{400}, wantKind: "invalid_request", wantDetail: "does not support embeddings",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &orch.Config{
+				Server:     orch.Server{RunnerTimeout: 2 * time.Second},
+				Embeddings: tt.embeddings,
+				Breaker:    orch.BreakerSpec{FailureThreshold: 1, Cooldown: time.Minute, Window: 10},
+				RateLimits: orch.RateLimits{"mock/api-embed-limited": {RPM: 1}},
+			}
+			srv := httptest.NewServer(New(cfg, orch.Keys{}).Router)
+			defer srv.Close()
+
+			var body struct {
+				Detail string `json:"detail"`
+				Kind   string `json:"kind"`
+				Data   []struct {
+					Index     int       `json:"index"`
+					Embedding []float32 `json:"embedding"`
+				} `json:"data"`
+			}
+			for i, want := range tt.wantStatuses {
+				resp, err := http.Post(srv.URL+"/v1/embeddings", "application/json", strings.NewReader(tt.body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				raw, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode != want {
+					t.Fatalf("request %d: status %d, want %d: %s", i, resp.StatusCode, want, raw)
+				}
+				body.Detail, body.Kind, body.Data = "", "", nil
+				if err := json.Unmarshal(raw, &body); err != nil {
+					t.Fatalf("request %d: %v: %s", i, err, raw)
+				}
+			}
+			if body.Kind != tt.wantKind || !strings.Contains(body.Detail, tt.wantDetail) {
+				t.Errorf("kind %q detail %q, want %q %q", body.Kind, body.Detail, tt.wantKind, tt.wantDetail)
+			}
+			if len(body.Data) != tt.wantVectors {
+				t.Fatalf("%d vectors, want %d", len(body.Data), tt.wantVectors)
+			}
+			for i, d := range body.Data {
+				if d.Index != i || len(d.Embedding) == 0 {
+					t.Errorf("vector %d: index %d, %d dims", i, d.Index, len(d.Embedding))
+				}
+			}
+		})
+	}
+}
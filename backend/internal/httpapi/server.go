@@ -1,13 +1,19 @@
 package httpapi
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/you/swarmone/internal/orch"
+	"github.com/you/swarmone/internal/provider"
 )
 
-// HTTP server exposing /v1/ask (judge-only consensus) and /health.
+// HTTP server exposing /v1/ask (judge or majority-vote consensus), its SSE variant
+// /v1/ask/stream, /v1/embeddings, /v1/providers, /v1/models and /health.
 
 type Server struct {
 	Router *gin.Engine
@@ -20,6 +26,10 @@ func New(cfg *orch.Config, keys orch.Keys) *Server {
 	s := &Server{Router: r, Cfg: cfg, Keys: keys}
 
 	r.POST("/v1/ask", s.ask)
+	r.POST("/v1/ask/stream", s.askStream)
+	r.POST("/v1/embeddings", s.embeddings)
+	r.GET("/v1/providers", s.providers)
+	r.GET("/v1/models", s.models)
 	r.GET("/health", s.health)
 
 	return s
@@ -33,45 +43,146 @@ func Serve(cfg *orch.Config, keys orch.Keys) error {
 
 func (s *Server) health(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
-		"ok":      true,
-		"runners": len(s.Cfg.Runners),
+		"ok":       true,
+		"runners":  len(s.Cfg.Runners),
+		"breakers": orch.Breakers(s.Cfg),
 	})
 }
 
+// providers lists the registered providers with their config schema and capabilities.
+func (s *Server) providers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": provider.Providers()})
+}
+
+// models lists the model catalog: context windows, prices and features.
+func (s *Server) models(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"models": provider.Models()})
+}
+
 type askReq struct {
 	TemplateID  *string `json:"template_id"`
 	Instruction string  `json:"instruction" binding:"required"`
+
+	// ResponseFormat asks runners for a JSON answer matching the schema.
+	ResponseFormat *provider.OutputSchema `json:"response_format"`
+
+	// Attachments are images/PDFs sent with the instruction (see attachments.go).
+	Attachments []attachmentReq `json:"attachments"`
+
+	// Context is shared material sent before the instruction and marked for
+	// prompt caching; reuse it verbatim across requests to get cache hits.
+	Context string `json:"context"`
+}
+
+func (r askReq) query() (orch.Query, error) {
+	q := orch.Query{Instruction: r.Instruction, Context: r.Context}
+	if len(r.Attachments) > maxAttachments {
+		return q, fmt.Errorf("at most %d attachments allowed", maxAttachments)
+	}
+	for i, a := range r.Attachments {
+		att, err := a.decode(i)
+		if err != nil {
+			return q, err
+		}
+		q.Attachments = append(q.Attachments, att)
+	}
+	if f := r.ResponseFormat; f != nil {
+		var schema map[string]any
+		if err := json.Unmarshal(f.Schema, &schema); err != nil || schema == nil {
+			return q, errors.New("response_format.schema must be a JSON Schema object")
+		}
+		q.Output = f
+	}
+	return q, nil
 }
 
 func (s *Server) ask(c *gin.Context) {
-	var req askReq
-	if err := c.ShouldBindJSON(&req); err != nil {
+	q, err := bindAsk(c)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
 		return
 	}
 	ctx := c.Request.Context()
 
-	answer, meta, err := orch.Execute(ctx, s.Cfg, s.Keys, req.Instruction)
+	answer, meta, err := orch.ExecuteQuery(ctx, s.Cfg, s.Keys, q, nil)
 	if err != nil && answer == "" {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"detail":           err.Error(),
-			"winner_index":     meta.WinnerIndex,
-			"runners":          meta.Runners,
-			"scores":           meta.Scores,
-			"included_indices": meta.IncludedIndices,
-			"runner_errors":    meta.RunnerErrors,
-			"consensus_id":     meta.ConsensusID,
-		})
+		c.JSON(http.StatusInternalServerError, errorBody(err, meta))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"answer":           answer,
-		"winner_index":     meta.WinnerIndex,
-		"runners":          meta.Runners,
-		"scores":           meta.Scores,
-		"included_indices": meta.IncludedIndices,
-		"runner_errors":    meta.RunnerErrors,
-		"consensus_id":     meta.ConsensusID,
+	c.JSON(http.StatusOK, answerBody(answer, meta))
+}
+
+// askStream runs the same pipeline as ask but reports progress as Server-Sent Events:
+// runner_start, runner_delta, runner_done and judge (vote in exact mode) while running, then a single
+// "final" (same body as /v1/ask) or "error" event. All events carry consensus_id.
+func (s *Server) askStream(c *gin.Context) {
+	q, err := bindAsk(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+
+	events := make(chan orch.Event, 64)
+	go func() {
+		defer close(events)
+		answer, meta, err := orch.ExecuteQuery(ctx, s.Cfg, s.Keys, q, func(ev orch.Event) {
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+			}
+		})
+		final := orch.Event{Type: "final", Data: answerBody(answer, meta)}
+		if err != nil && answer == "" {
+			final = orch.Event{Type: "error", Data: errorBody(err, meta)}
+		}
+		select {
+		case events <- final:
+		case <-ctx.Done():
+		}
+	}()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Stream(func(w io.Writer) bool {
+		ev, ok := <-events
+		if !ok {
+			return false
+		}
+		c.SSEvent(ev.Type, ev.Data)
+		return true
 	})
 }
+
+func answerBody(answer string, meta orch.Meta) gin.H {
+	return gin.H{
+		"answer":              answer,
+		"winner_index":        meta.WinnerIndex,
+		"runners":             meta.Runners,
+		"scores":              meta.Scores,
+		"included_indices":    meta.IncludedIndices,
+		"votes_per_candidate": meta.VotesPerCandidate,
+		"runner_errors":       meta.RunnerErrors,
+		"runner_calls":        meta.RunnerCalls,
+		"judge_call":          meta.JudgeCall,
+		"total_usage":         meta.TotalUsage,
+		"consensus_id":        meta.ConsensusID,
+	}
+}
+
+func errorBody(err error, meta orch.Meta) gin.H {
+	return gin.H{
+		"detail":              err.Error(),
+		"winner_index":        meta.WinnerIndex,
+		"runners":             meta.Runners,
+		"scores":              meta.Scores,
+		"included_indices":    meta.IncludedIndices,
+		"votes_per_candidate": meta.VotesPerCandidate,
+		"runner_errors":       meta.RunnerErrors,
+		"runner_calls":        meta.RunnerCalls,
+		"judge_call":          meta.JudgeCall,
+		"total_usage":         meta.TotalUsage,
+		"consensus_id":        meta.ConsensusID,
+	}
+}
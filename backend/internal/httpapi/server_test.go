@@ -0,0 +1,223 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/you/swarmone/internal/orch"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+}
+
+// testFixture scripts the mock runners and judge of the HTTP tests. Model
+// names are unique to this package: breakers are shared process-wide.
+const testFixture = `{
+  "rules": [
+    {"match": {"system": "strict impartial judge", "regex": "\"index\":1"}, "respond": {"json": {"scores": [0.4, 0.9], "winner": 1}}},
+    {"match": {"system": "strict impartial judge"}, "respond": {"json": {"scores": [0.8], "winner": 0}}},
+    {"match": {"model": "api-fail"}, "respond": {"error": {"kind": "server", "status": 503, "message": "scripted outage"}}},
+    {"match": {"model": "api-a"}, "respond": {"text": "Answer from runner A."}},
+    {"match": {"model": "api-b"}, "respond": {"text": "Answer from runner B."}}
+  ]
+}`
+
+// mockRunner is a mock runner; newTestServer points it at testFixture.
+func mockRunner(model string) orch.RunnerSpec {
+	return orch.RunnerSpec{Name: model, Provider: "mock", Model: model, MaxTokens: 64}
+}
+
+// newTestServer serves runners with a mock judge, over a real listener so
+// SSE responses stream.
+func newTestServer(t *testing.T, runners ...orch.RunnerSpec) *httptest.Server {
+	t.Helper()
+	fixture := writeFixture(t)
+	for i := range runners {
+		if runners[i].Provider == "mock" {
+			runners[i].Fixture = fixture
+		}
+	}
+	cfg := &orch.Config{
+		Runners:   runners,
+		Consensus: orch.Consensus{Judge: orch.JudgeSpec{Provider: "mock", Model: "api-judge", MaxTokens: 64, Fixture: fixture}},
+	}
+	srv := httptest.NewServer(New(cfg, orch.Keys{}).Router)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func writeFixture(t *testing.T) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "fixture.json")
+	if err := os.WriteFile(p, []byte(testFixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+type sseEvent struct {
+	Event string
+	Data  map[string]any
+}
+
+// readSSE splits an SSE body into events; every frame must be exactly an
+// event line and a JSON data line.
+func readSSE(t *testing.T, body string) []sseEvent {
+	t.Helper()
+	var out []sseEvent
+	for _, frame := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		lines := strings.Split(frame, "\n")
+		if len(lines) != 2 || !strings.HasPrefix(lines[0], "event:") || !strings.HasPrefix(lines[1], "data:") {
+			t.Fatalf("malformed frame %q", frame)
+		}
+		ev := sseEvent{Event: strings.TrimPrefix(lines[0], "event:")}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(lines[1], "data:")), &ev.Data); err != nil {
+			t.Fatalf("frame %q: data is not a JSON object: %v", frame, err)
+		}
+		out = append(out, ev)
+	}
+	return out
+}
+
+func TestAskStream(t *testing.T) {
+	tests := []struct {
+		name       string
+		runners    []string
+		body       string
+		wantStatus int
+		wantLast   string // final or error
+		wantAnswer string
+		wantDetail string
+	}{
+		{
+			name:       "runners then judge then final",
+			runners:    []string{"api-a", "api-b"},
+			body:       `{"instruction":"Say something."}`,
+			wantStatus: http.StatusOK, wantLast: "final", wantAnswer: "Answer from runner B.",
+		},
+		{
+			name:       "a failed runner still reports runner_done",
+			runners:    []string{"api-fail", "api-a"},
+			body:       `{"instruction":"Say something."}`,
+			wantStatus: http.StatusOK, wantLast: "final", wantAnswer: "Answer from runner A.",
+		},
+		{
+			name:       "all runners failed ends with an error event",
+			runners:    []string{"api-fail"},
+			body:       `{"instruction":"Say something."}`,
+			wantStatus: http.StatusOK, wantLast: "error", wantDetail: "all runners failed",
+		},
+		{
+			name:       "a bad request is plain JSON",
+			runners:    []string{"api-a"},
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest, wantDetail: "Instruction",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var runners []orch.RunnerSpec
+			for _, m := range tt.runners {
+				runners = append(runners, mockRunner(m))
+			}
+			srv := newTestServer(t, runners...)
+			resp, err := http.Post(srv.URL+"/v1/ask/stream", "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			raw, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, raw)
+			}
+			if tt.wantLast == "" {
+				var body map[string]any
+				if err := json.Unmarshal(raw, &body); err != nil || !strings.Contains(body["detail"].(string), tt.wantDetail) {
+					t.Fatalf("body = %s, want detail %q", raw, tt.wantDetail)
+				}
+				return
+			}
+			if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+				t.Errorf("Content-Type = %q", ct)
+			}
+
+			events := readSSE(t, string(raw))
+			last := events[len(events)-1]
+			if last.Event != tt.wantLast {
+				t.Fatalf("last event = %q, want %q", last.Event, tt.wantLast)
+			}
+			if tt.wantAnswer != "" && last.Data["answer"] != tt.wantAnswer {
+				t.Errorf("answer = %v, want %q", last.Data["answer"], tt.wantAnswer)
+			}
+			if tt.wantDetail != "" && !strings.Contains(last.Data["detail"].(string), tt.wantDetail) {
+				t.Errorf("detail = %v, want %q", last.Data["detail"], tt.wantDetail)
+			}
+
+			// Per runner: start, deltas adding up to the answer, done. The
+			// judge comes after every runner is done; final/error only last.
+			consID := last.Data["consensus_id"]
+			started, done := map[int]bool{}, map[int]bool{}
+			deltas := map[int]string{}
+			judged := false
+			for i, ev := range events {
+				if ev.Data["consensus_id"] != consID || consID == nil {
+					t.Errorf("event %d (%s) consensus_id = %v, want %v", i, ev.Event, ev.Data["consensus_id"], consID)
+				}
+				r := -1
+				if v, ok := ev.Data["runner"].(float64); ok {
+					r = int(v)
+				}
+				switch ev.Event {
+				case orch.EventRunnerStart:
+					if started[r] {
+						t.Errorf("runner %d started twice", r)
+					}
+					started[r] = true
+				case orch.EventRunnerDelta:
+					if !started[r] || done[r] {
+						t.Errorf("event %d: delta for runner %d outside start/done", i, r)
+					}
+					deltas[r] += ev.Data["delta"].(string)
+				case orch.EventRunnerDone:
+					if !started[r] || done[r] {
+						t.Errorf("event %d: runner %d done out of order", i, r)
+					}
+					done[r] = true
+					text, _ := ev.Data["text"].(string)
+					if text != strings.TrimSpace(deltas[r]) {
+						t.Errorf("runner %d: deltas %q do not add up to %q", r, deltas[r], text)
+					}
+					if (text == "") == (ev.Data["error"] == nil) {
+						t.Errorf("runner %d: done needs exactly one of text and error: %v", r, ev.Data)
+					}
+				case orch.EventJudge:
+					if len(done) != len(tt.runners) {
+						t.Errorf("judge before all runners were done")
+					}
+					judged = true
+				case "final", "error":
+					if i != len(events)-1 {
+						t.Errorf("%s event at %d of %d", ev.Event, i, len(events))
+					}
+				default:
+					t.Errorf("unexpected event %q", ev.Event)
+				}
+			}
+			if len(done) != len(tt.runners) {
+				t.Errorf("runner_done for %d of %d runners", len(done), len(tt.runners))
+			}
+			if judged != (tt.wantLast == "final") {
+				t.Errorf("judge event sent = %v", judged)
+			}
+		})
+	}
+}
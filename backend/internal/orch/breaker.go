@@ -0,0 +1,232 @@
+package orch
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/you/swarmone/internal/provider"
+)
+
+// Breaker states.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// errCircuitOpen is reported in RunnerErrors for runners skipped by an open breaker.
+var errCircuitOpen = errors.New("circuit open")
+
+// BreakerSpec tunes the per provider/model circuit breakers.
+type BreakerSpec struct {
+	FailureThreshold int           // consecutive failures that open the circuit
+	Cooldown         time.Duration // time spent open before a half-open probe
+	Window           int           // recent calls kept for error rate / latency
+}
+
+func (b BreakerSpec) normalized() BreakerSpec {
+	if b.FailureThreshold <= 0 {
+		b.FailureThreshold = 5
+	}
+	if b.Cooldown <= 0 {
+		b.Cooldown = 30 * time.Second
+	}
+	if b.Window <= 0 {
+		b.Window = 20
+	}
+	return b
+}
+
+// BreakerStatus is the /health view of one breaker.
+type BreakerStatus struct {
+	Provider     string  `json:"provider"`
+	Model        string  `json:"model"`
+	State        string  `json:"state"`
+	ErrorRate    float64 `json:"error_rate"`     // over the recent window
+	Calls        int     `json:"calls"`          // calls in the recent window
+	AvgLatencyMs int64   `json:"avg_latency_ms"` // over the recent window
+	LastError    string  `json:"last_error,omitempty"`
+}
+
+type callOutcome struct {
+	failed  bool
+	latency time.Duration
+}
+
+type breaker struct {
+	mu       sync.Mutex
+	spec     BreakerSpec
+	provider string
+	model    string
+
+	state       string
+	consecFails int
+	openedAt    time.Time
+	probing     bool // a half-open probe is in flight
+	recent      []callOutcome
+	lastErr     string
+}
+
+var breakers = struct {
+	sync.Mutex
+	m map[string]*breaker
+}{m: map[string]*breaker{}}
+
+// breakerFor returns the shared breaker of a provider/model pair. Provider
+// aliases ("claude", "google") share the breaker of the canonical name.
+func breakerFor(spec BreakerSpec, providerName, model string) *breaker {
+	p := canonicalProvider(providerName)
+	key := p + "/" + model
+	breakers.Lock()
+	defer breakers.Unlock()
+	b, ok := breakers.m[key]
+	if !ok {
+		b = &breaker{spec: spec.normalized(), provider: p, model: model, state: BreakerClosed}
+		breakers.m[key] = b
+	}
+	return b
+}
+
+// canonicalProvider maps a provider name or alias to the registered name;
+// unknown names are only lower-cased.
+func canonicalProvider(name string) string {
+	p := strings.ToLower(strings.TrimSpace(name))
+	if s, ok := provider.Lookup(p); ok {
+		return s.Name
+	}
+	return p
+}
+
+// allow reports whether a call may proceed. After the cooldown an open
+// breaker lets exactly one probe through (half-open).
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.spec.Cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// record feeds a call outcome back. Cancellation by the caller's own context
+// (client went away) says nothing about the provider and is not counted;
+// request-specific errors count as a healthy call.
+func (b *breaker) record(parent context.Context, err error, latency time.Duration, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil && parent.Err() != nil {
+		if b.state == BreakerHalfOpen {
+			b.probing = false
+		}
+		return
+	}
+	if !providerFault(err) {
+		err = nil
+	}
+
+	b.recent = append(b.recent, callOutcome{failed: err != nil, latency: latency})
+	if len(b.recent) > b.spec.Window {
+		b.recent = b.recent[len(b.recent)-b.spec.Window:]
+	}
+
+	if err == nil {
+		b.state = BreakerClosed
+		b.consecFails = 0
+		b.probing = false
+		return
+	}
+	b.lastErr = truncate(err.Error(), 200)
+	b.consecFails++
+	if b.state == BreakerHalfOpen || b.consecFails >= b.spec.FailureThreshold {
+		b.state = BreakerOpen
+		b.openedAt = now
+		b.probing = false
+	}
+}
+
+// providerFault reports whether err says something about the provider's health.
+// Request-specific failures (bad input, safety blocks, context overflow, empty
+// answers) and local refusals (rate limit, open circuit) leave the breaker alone.
+func providerFault(err error) bool {
+	if err == nil || errors.Is(err, errRateLimited) || errors.Is(err, errCircuitOpen) {
+		return false
+	}
+	switch provider.AsError(err).Kind {
+	case provider.KindInvalidRequest, provider.KindContextLength, provider.KindSafety,
+		provider.KindEmptyOutput, provider.KindCanceled, provider.KindUnsupportedInput:
+		return false
+	}
+	return true
+}
+
+func (b *breaker) status(now time.Time) BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := BreakerStatus{Provider: b.provider, Model: b.model, State: b.state, Calls: len(b.recent), LastError: b.lastErr}
+	if st.State == BreakerOpen && now.Sub(b.openedAt) >= b.spec.Cooldown {
+		st.State = BreakerHalfOpen // next call will probe
+	}
+	if len(b.recent) > 0 {
+		var fails int
+		var total time.Duration
+		for _, o := range b.recent {
+			if o.failed {
+				fails++
+			}
+			total += o.latency
+		}
+		st.ErrorRate = float64(fails) / float64(len(b.recent))
+		st.ErrorRate = float64(int64(st.ErrorRate*10000+0.5)) / 10000
+		st.AvgLatencyMs = (total / time.Duration(len(b.recent))).Milliseconds()
+	}
+	return st
+}
+
+// Breakers returns the status of the breakers of cfg's runners and judge plus
+// any other breaker seen so far, sorted by provider/model.
+func Breakers(cfg *Config) []BreakerStatus {
+	if cfg != nil {
+		for _, r := range cfg.Runners {
+			breakerFor(cfg.Breaker, r.Provider, r.Model)
+		}
+		if j := cfg.Consensus.Judge; j.Provider != "" && j.Model != "" {
+			breakerFor(cfg.Breaker, j.Provider, j.Model)
+		}
+	}
+
+	breakers.Lock()
+	list := make([]*breaker, 0, len(breakers.m))
+	for _, b := range breakers.m {
+		list = append(list, b)
+	}
+	breakers.Unlock()
+
+	now := time.Now()
+	out := make([]BreakerStatus, 0, len(list))
+	for _, b := range list {
+		out = append(out, b.status(now))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Provider != out[j].Provider {
+			return out[i].Provider < out[j].Provider
+		}
+		return out[i].Model < out[j].Model
+	})
+	return out
+}
@@ -0,0 +1,147 @@
+package orch
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/you/swarmone/internal/provider"
+)
+
+func TestBreakerStateMachine(t *testing.T) {
+	bg := context.Background()
+	canceled, cancel := context.WithCancel(bg)
+	cancel()
+	fail := &provider.Error{Kind: provider.KindServer, Message: "boom"}
+	t0 := time.Unix(1000, 0)
+	cool := 10 * time.Second
+
+	type step struct {
+		at     time.Duration // offset from t0
+		allow  *bool         // expected allow() result, if checked
+		record error         // outcome fed back when doRec
+		ctx    context.Context
+		doRec  bool
+		state  string // expected state afterwards
+	}
+	yes, no := true, false
+	allow := func(at time.Duration, want *bool, state string) step {
+		return step{at: at, allow: want, state: state}
+	}
+	rec := func(at time.Duration, err error, state string) step {
+		return step{at: at, record: err, ctx: bg, doRec: true, state: state}
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "failures below the threshold stay closed",
+			steps: []step{
+				rec(0, fail, BreakerClosed),
+				rec(0, fail, BreakerClosed),
+				rec(0, nil, BreakerClosed), // success resets the streak
+				rec(0, fail, BreakerClosed),
+				rec(0, fail, BreakerClosed),
+				allow(0, &yes, BreakerClosed),
+			},
+		},
+		{
+			name: "threshold opens, cooldown leads to a single half-open probe that closes",
+			steps: []step{
+				rec(0, fail, BreakerClosed),
+				rec(0, fail, BreakerClosed),
+				rec(0, fail, BreakerOpen),
+				allow(time.Second, &no, BreakerOpen),
+				allow(cool, &yes, BreakerHalfOpen),
+				allow(cool, &no, BreakerHalfOpen), // probe already in flight
+				rec(cool+time.Second, nil, BreakerClosed),
+				allow(cool+time.Second, &yes, BreakerClosed),
+			},
+		},
+		{
+			name: "failed probe reopens for another cooldown",
+			steps: []step{
+				rec(0, fail, BreakerClosed),
+				rec(0, fail, BreakerClosed),
+				rec(0, fail, BreakerOpen),
+				allow(cool, &yes, BreakerHalfOpen),
+				rec(cool, fail, BreakerOpen),
+				allow(cool+cool/2, &no, BreakerOpen),
+				allow(2*cool, &yes, BreakerHalfOpen),
+			},
+		},
+		{
+			name: "caller cancellation releases the probe without counting",
+			steps: []step{
+				rec(0, fail, BreakerClosed),
+				rec(0, fail, BreakerClosed),
+				rec(0, fail, BreakerOpen),
+				allow(cool, &yes, BreakerHalfOpen),
+				{at: cool, record: fail, ctx: canceled, doRec: true, state: BreakerHalfOpen},
+				allow(cool, &yes, BreakerHalfOpen), // a new probe may go
+			},
+		},
+		{
+			name: "request-specific errors and local refusals count as healthy",
+			steps: []step{
+				rec(0, fail, BreakerClosed),
+				rec(0, fail, BreakerClosed),
+				rec(0, &provider.Error{Kind: provider.KindInvalidRequest}, BreakerClosed),
+				rec(0, fail, BreakerClosed),
+				rec(0, fail, BreakerClosed),
+				rec(0, fmt.Errorf("%w (10 rpm)", errRateLimited), BreakerClosed),
+				rec(0, fail, BreakerClosed),
+				rec(0, fail, BreakerClosed),
+				rec(0, fail, BreakerOpen),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &breaker{spec: BreakerSpec{FailureThreshold: 3, Cooldown: cool, Window: 10}.normalized(), state: BreakerClosed}
+			for i, s := range tt.steps {
+				now := t0.Add(s.at)
+				if s.doRec {
+					b.record(s.ctx, s.record, time.Millisecond, now)
+				} else if got := b.allow(now); s.allow != nil && got != *s.allow {
+					t.Fatalf("step %d: allow = %v, want %v", i, got, *s.allow)
+				}
+				if b.state != s.state {
+					t.Fatalf("step %d: state = %q, want %q", i, b.state, s.state)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerStatus(t *testing.T) {
+	t0 := time.Unix(1000, 0)
+	b := &breaker{spec: BreakerSpec{FailureThreshold: 2, Cooldown: time.Minute, Window: 4}.normalized(), provider: "p", model: "m", state: BreakerClosed}
+	fail := &provider.Error{Provider: "p", Kind: provider.KindTimeout, Message: "timeout"}
+	for _, err := range []error{nil, nil, nil, fail, fail} { // the first call falls out of the window
+		b.record(context.Background(), err, 100*time.Millisecond, t0)
+	}
+	st := b.status(t0)
+	if st.State != BreakerOpen || st.Calls != 4 || st.ErrorRate != 0.5 || st.AvgLatencyMs != 100 || st.LastError != "p timeout" {
+		t.Errorf("status = %+v", st)
+	}
+	if st := b.status(t0.Add(time.Minute)); st.State != BreakerHalfOpen {
+		t.Errorf("state after cooldown = %q, want %q", st.State, BreakerHalfOpen)
+	}
+}
+
+func TestBreakerForSharesAliases(t *testing.T) {
+	spec := BreakerSpec{}
+	if breakerFor(spec, "claude", "breaker-test") != breakerFor(spec, " Anthropic ", "breaker-test") {
+		t.Error("claude and anthropic got different breakers")
+	}
+	if breakerFor(spec, "google", "breaker-test") != breakerFor(spec, "gemini", "breaker-test") {
+		t.Error("google and gemini got different breakers")
+	}
+	if breakerFor(spec, "gemini", "breaker-test") == breakerFor(spec, "anthropic", "breaker-test") {
+		t.Error("different providers share a breaker")
+	}
+}
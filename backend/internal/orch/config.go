@@ -2,10 +2,15 @@ package orch
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/you/swarmone/internal/provider"
+	"gopkg.in/yaml.v3"
 )
 
 // Keys holds provider API keys.
@@ -21,18 +26,189 @@ type RunnerSpec struct {
 	Provider  string `json:"provider"`
 	Model     string `json:"model"`
 	MaxTokens int    `json:"max_tokens"`
+
+	// Sampling parameters (temperature, top_p, top_k, stop, seed,
+	// presence_penalty, frequency_penalty) as top-level fields; unset means the
+	// provider default. Checked against the provider by Load.
+	provider.Sampling
+
+	// Reasoning sets the thinking effort/budget of reasoning models; the
+	// budget is added on top of MaxTokens. nil → provider default.
+	Reasoning *provider.Reasoning `json:"reasoning,omitempty"`
+
+	// Endpoint settings for self-hosted providers (e.g. "openai-compatible").
+	BaseURL   string            `json:"base_url,omitempty"`    // e.g. "http://localhost:8000/v1"
+	APIKey    string            `json:"api_key,omitempty"`     // optional; overrides Keys
+	APIKeyEnv string            `json:"api_key_env,omitempty"` // env var to read the key from
+	Headers   map[string]string `json:"headers,omitempty"`     // extra request headers
+
+	// Ollama settings.
+	Options   map[string]any `json:"options,omitempty"`    // passed through as Ollama "options" (num_ctx, temperature, ...)
+	KeepAlive string         `json:"keep_alive,omitempty"` // e.g. "10m", "-1"
+
+	Retry *RetrySpec `json:"retry,omitempty"` // nil → provider.DefaultRetryPolicy
+
+	Fixture string `json:"fixture,omitempty"` // mock provider: fixture JSON path (default $MOCK_FIXTURE)
+
+	// Exec provider: the local command and how to run it.
+	Command []string          `json:"command,omitempty"` // argv, e.g. ["python3", "rules.py"]
+	Env     map[string]string `json:"env,omitempty"`
+	Dir     string            `json:"dir,omitempty"`
+	Timeout string            `json:"timeout,omitempty"` // Go duration per call; default 60s (also used by "http")
+
+	// HTTP provider: endpoint, body template and response paths.
+	Template *provider.HTTPTemplate `json:"template,omitempty"`
+
+	// Context window handling (see preflight.go). ContextWindow overrides the
+	// model catalog, e.g. for self-hosted models.
+	ContextWindow int    `json:"context_window,omitempty"`
+	Overflow      string `json:"overflow,omitempty"`       // skip (default), truncate_middle, escalate, none
+	OverflowModel string `json:"overflow_model,omitempty"` // escalate target; default: next larger catalog model
+
+	// Tools offered to the runner, by name as registered with RegisterTool ("*" = all).
+	Tools         []string `json:"tools,omitempty"`
+	MaxToolRounds int      `json:"max_tool_rounds,omitempty"` // model calls per answer; default 4
+}
+
+// RetrySpec configures retries of a runner's provider calls.
+// Delays are Go durations ("500ms", "5s"); empty means the provider default.
+// Retries never outlive the runner's context deadline.
+type RetrySpec struct {
+	MaxAttempts int    `json:"max_attempts"` // 1 disables retries
+	BaseDelay   string `json:"base_delay"`
+	MaxDelay    string `json:"max_delay"`
+}
+
+func (r *RetrySpec) policy() provider.RetryPolicy {
+	if r == nil {
+		return provider.RetryPolicy{}
+	}
+	return provider.RetryPolicy{
+		MaxAttempts: r.MaxAttempts,
+		BaseDelay:   parseDurDefault(r.BaseDelay, 0),
+		MaxDelay:    parseDurDefault(r.MaxDelay, 0),
+	}
+}
+
+// TransportSpec tunes the HTTP transport shared by all provider clients
+// (see provider.TransportConfig). Durations are Go durations; empty fields
+// keep the defaults. Providers overrides fields per provider, e.g. a proxy
+// only for "openai" or a private CA only for "openai-compatible".
+type TransportSpec struct {
+	Proxy  string `json:"proxy,omitempty"`   // http(s)/socks5 URL; "direct" = none; empty = HTTP(S)_PROXY env
+	CAFile string `json:"ca_file,omitempty"` // extra PEM roots
+
+	DialTimeout           string `json:"dial_timeout,omitempty"`
+	TLSHandshakeTimeout   string `json:"tls_handshake_timeout,omitempty"`
+	ResponseHeaderTimeout string `json:"response_header_timeout,omitempty"`
+	IdleConnTimeout       string `json:"idle_conn_timeout,omitempty"`
+
+	MaxIdleConns        int  `json:"max_idle_conns,omitempty"`
+	MaxIdleConnsPerHost int  `json:"max_idle_conns_per_host,omitempty"`
+	MaxConnsPerHost     int  `json:"max_conns_per_host,omitempty"`
+	DisableHTTP2        bool `json:"disable_http2,omitempty"`
+
+	Providers map[string]TransportSpec `json:"providers,omitempty"`
+}
+
+func (t TransportSpec) config() provider.TransportConfig {
+	return provider.TransportConfig{
+		Proxy:                 t.Proxy,
+		CAFile:                t.CAFile,
+		DialTimeout:           parseDurDefault(t.DialTimeout, 0),
+		TLSHandshakeTimeout:   parseDurDefault(t.TLSHandshakeTimeout, 0),
+		ResponseHeaderTimeout: parseDurDefault(t.ResponseHeaderTimeout, 0),
+		IdleConnTimeout:       parseDurDefault(t.IdleConnTimeout, 0),
+		MaxIdleConns:          t.MaxIdleConns,
+		MaxIdleConnsPerHost:   t.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       t.MaxConnsPerHost,
+		DisableHTTP2:          t.DisableHTTP2,
+	}
+}
+
+// apply installs the shared transports.
+func (t TransportSpec) apply() error {
+	per := make(map[string]provider.TransportConfig, len(t.Providers))
+	for name, p := range t.Providers {
+		per[name] = p.config()
+	}
+	return provider.SetTransports(t.config(), per)
+}
+
+// apiKey resolves the runner's own key: APIKey first, then APIKeyEnv.
+func (r RunnerSpec) apiKey() string {
+	if r.APIKey != "" {
+		return r.APIKey
+	}
+	if r.APIKeyEnv != "" {
+		return os.Getenv(r.APIKeyEnv)
+	}
+	return ""
 }
 
 // JudgeSpec defines the arbitrator model.
+// Sampling, endpoint, Ollama and exec fields have the same meaning as in RunnerSpec.
 type JudgeSpec struct {
 	Provider  string `json:"provider"`
 	Model     string `json:"model"`
 	MaxTokens int    `json:"max_tokens"`
+
+	provider.Sampling
+	Reasoning *provider.Reasoning `json:"reasoning,omitempty"`
+
+	BaseURL   string            `json:"base_url,omitempty"`
+	APIKey    string            `json:"api_key,omitempty"`
+	APIKeyEnv string            `json:"api_key_env,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Options   map[string]any    `json:"options,omitempty"`
+	KeepAlive string            `json:"keep_alive,omitempty"`
+	Retry     *RetrySpec        `json:"retry,omitempty"`
+	Fixture   string            `json:"fixture,omitempty"`
+	Command   []string          `json:"command,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	Dir       string            `json:"dir,omitempty"`
+	Timeout   string            `json:"timeout,omitempty"`
+
+	Template *provider.HTTPTemplate `json:"template,omitempty"`
+}
+
+// runnerSpec lets the judge reuse the runner client factory.
+func (j JudgeSpec) runnerSpec() RunnerSpec {
+	return RunnerSpec{
+		Name:      "judge",
+		Provider:  j.Provider,
+		Model:     j.Model,
+		MaxTokens: j.MaxTokens,
+		Sampling:  j.Sampling,
+		Reasoning: j.Reasoning,
+		BaseURL:   j.BaseURL,
+		APIKey:    j.APIKey,
+		APIKeyEnv: j.APIKeyEnv,
+		Headers:   j.Headers,
+		Options:   j.Options,
+		KeepAlive: j.KeepAlive,
+		Retry:     j.Retry,
+		Fixture:   j.Fixture,
+		Command:   j.Command,
+		Env:       j.Env,
+		Dir:       j.Dir,
+		Timeout:   j.Timeout,
+		Template:  j.Template,
+	}
 }
 
-// Consensus keeps judge configuration (we only support judge-only now).
+// Consensus selects how the answer is picked: ModeJudge (default) asks the
+// judge; ModeExact takes a plurality vote over answers compared after the
+// Normalize steps (NormWhitespace, NormCase, NormPunctuation, NormJSON) and
+// never calls the judge.
+//
+// Mode and Normalize are read from the consensus section of the config file
+// (see readConsensus); the judge comes from SWARMONE_JUDGE / JUDGE_*.
 type Consensus struct {
-	Judge JudgeSpec `json:"judge"`
+	Mode      string    `json:"mode" yaml:"mode"`
+	Normalize []string  `json:"normalize,omitempty" yaml:"normalize"`
+	Judge     JudgeSpec `json:"judge" yaml:"-"`
+	File      string    `json:"file,omitempty" yaml:"-"` // config file Mode and Normalize came from; empty when none was found
 }
 
 // Server options.
@@ -47,6 +223,14 @@ type Config struct {
 	Server    Server       `json:"server"`
 	Runners   []RunnerSpec `json:"runners"`
 	Consensus Consensus    `json:"consensus"`
+	Breaker   BreakerSpec  `json:"breaker"`
+
+	HTTP       TransportSpec `json:"http"`
+	RateLimits RateLimits    `json:"rate_limits"`
+
+	// Embeddings is the model behind Embed and /v1/embeddings; runner
+	// fields apply (provider, model, base_url, api_key, retry, ...).
+	Embeddings RunnerSpec `json:"embeddings"`
 }
 
 // Load builds Config and Keys from environment variables with safe defaults.
@@ -75,16 +259,126 @@ func Load() (*Config, Keys, error) {
 	if len(runners) == 0 {
 		// defaults try to mirror your previous setup
 		runners = []RunnerSpec{
-			{Name: "runner-openai", Provider: "openai", Model: "gpt-5-nano-2025-08-07", MaxTokens: 512},
+			// Minimal effort keeps the reasoning model from spending the whole budget thinking.
+			{Name: "runner-openai", Provider: "openai", Model: "gpt-5-nano-2025-08-07", MaxTokens: 512, Reasoning: &provider.Reasoning{Effort: "minimal"}},
 			{Name: "runner-gemini", Provider: "gemini", Model: "gemini-2.5-flash", MaxTokens: 512},
 			{Name: "runner-claude", Provider: "anthropic", Model: "claude-3-5-haiku-20241022", MaxTokens: 512},
 		}
 	}
 
-	// Judge: env overrides or default to Anthropic (strong & stable).
-	judgeProv := firstNonEmpty(os.Getenv("JUDGE_PROVIDER"), "anthropic")
-	judgeModel := firstNonEmpty(os.Getenv("JUDGE_MODEL"), "claude-3-5-sonnet-20241022")
-	judgeMax := parseIntDefault(os.Getenv("JUDGE_MAX_TOKENS"), 384)
+	// Judge: SWARMONE_JUDGE (JSON object, same fields as a runner) as a base,
+	// then JUDGE_* env overrides, else default to Anthropic (strong & stable).
+	var judge JudgeSpec
+	if raw := strings.TrimSpace(os.Getenv("SWARMONE_JUDGE")); raw != "" {
+		_ = json.Unmarshal([]byte(raw), &judge) // malformed → defaults below
+	}
+	judge.Provider = firstNonEmpty(os.Getenv("JUDGE_PROVIDER"), firstNonEmpty(judge.Provider, "anthropic"))
+	judge.Model = firstNonEmpty(os.Getenv("JUDGE_MODEL"), firstNonEmpty(judge.Model, "claude-3-5-sonnet-20241022"))
+	if judge.MaxTokens <= 0 {
+		judge.MaxTokens = 384
+	}
+	judge.MaxTokens = parseIntDefault(os.Getenv("JUDGE_MAX_TOKENS"), judge.MaxTokens)
+	judge.BaseURL = firstNonEmpty(os.Getenv("JUDGE_BASE_URL"), judge.BaseURL)
+
+	// Model catalog overrides: SWARMONE_MODELS (JSON array of
+	// provider.ModelInfo); each entry replaces the built-in one.
+	if raw := strings.TrimSpace(os.Getenv("SWARMONE_MODELS")); raw != "" {
+		var models []provider.ModelInfo
+		if err := json.Unmarshal([]byte(raw), &models); err != nil {
+			return nil, keys, fmt.Errorf("SWARMONE_MODELS: %w", err)
+		}
+		for _, m := range models {
+			if err := provider.RegisterModel(m); err != nil {
+				return nil, keys, fmt.Errorf("SWARMONE_MODELS: %w", err)
+			}
+		}
+	}
+
+	for _, r := range runners {
+		spec, ok := provider.Lookup(r.Provider)
+		if !ok {
+			return nil, keys, fmt.Errorf("runner %q: unknown provider %q", r.Name, r.Provider)
+		}
+		if len(r.Tools) > 0 && !spec.Capabilities.Tools {
+			return nil, keys, fmt.Errorf("runner %q: provider %q does not support tools", r.Name, r.Provider)
+		}
+		if _, err := toolsFor(r.Tools); err != nil {
+			return nil, keys, fmt.Errorf("runner %q: %w", r.Name, err)
+		}
+		if err := provider.ValidateSampling(r.Provider, r.Sampling); err != nil {
+			return nil, keys, fmt.Errorf("runner %q: %w", r.Name, err)
+		}
+		if err := provider.ValidateReasoning(r.Provider, r.Reasoning, r.Sampling); err != nil {
+			return nil, keys, fmt.Errorf("runner %q: %w", r.Name, err)
+		}
+		if err := provider.ValidateMaxTokens(r.Provider, r.Model, r.MaxTokens, r.Reasoning); err != nil {
+			return nil, keys, fmt.Errorf("runner %q: %w", r.Name, err)
+		}
+		if m, ok := provider.LookupModel(r.Provider, r.Model); ok && len(r.Tools) > 0 && !m.Tools {
+			return nil, keys, fmt.Errorf("runner %q: model %q does not support tools", r.Name, r.Model)
+		}
+		if !validOverflow(r.Overflow) {
+			return nil, keys, fmt.Errorf("runner %q: unknown overflow policy %q", r.Name, r.Overflow)
+		}
+	}
+	if _, ok := provider.Lookup(judge.Provider); !ok {
+		return nil, keys, fmt.Errorf("judge: unknown provider %q", judge.Provider)
+	}
+	judge.Sampling = judgeSampling(judge)
+	if err := provider.ValidateSampling(judge.Provider, judge.Sampling); err != nil {
+		return nil, keys, fmt.Errorf("judge: %w", err)
+	}
+	if err := provider.ValidateReasoning(judge.Provider, judge.Reasoning, judge.Sampling); err != nil {
+		return nil, keys, fmt.Errorf("judge: %w", err)
+	}
+	if err := provider.ValidateMaxTokens(judge.Provider, judge.Model, judge.MaxTokens, judge.Reasoning); err != nil {
+		return nil, keys, fmt.Errorf("judge: %w", err)
+	}
+
+	// Consensus mode and normalization: config file (SWARMONE_CONFIG).
+	consensus, err := readConsensus(os.Getenv("SWARMONE_CONFIG"))
+	if err != nil {
+		return nil, keys, err
+	}
+
+	// Embedding model: SWARMONE_EMBEDDINGS (JSON object, runner fields),
+	// default OpenAI text-embedding-3-small.
+	var embeddings RunnerSpec
+	if raw := strings.TrimSpace(os.Getenv("SWARMONE_EMBEDDINGS")); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &embeddings); err != nil {
+			return nil, keys, fmt.Errorf("SWARMONE_EMBEDDINGS: %w", err)
+		}
+	}
+	embeddings.Name = "embeddings"
+	embeddings.Provider = firstNonEmpty(embeddings.Provider, "openai")
+	embeddings.Model = firstNonEmpty(embeddings.Model, "text-embedding-3-small")
+	if spec, ok := provider.Lookup(embeddings.Provider); !ok || !spec.Capabilities.Embeddings {
+		return nil, keys, fmt.Errorf("SWARMONE_EMBEDDINGS: provider %q does not support embeddings", embeddings.Provider)
+	}
+
+	// Shared HTTP transport: SWARMONE_HTTP (JSON object, see TransportSpec).
+	var transport TransportSpec
+	if raw := strings.TrimSpace(os.Getenv("SWARMONE_HTTP")); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &transport); err != nil {
+			return nil, keys, fmt.Errorf("SWARMONE_HTTP: %w", err)
+		}
+	}
+	if err := transport.apply(); err != nil {
+		return nil, keys, fmt.Errorf("SWARMONE_HTTP: %w", err)
+	}
+
+	// Outbound rate limits: SWARMONE_RATE_LIMITS, e.g.
+	// {"openai":{"rpm":500,"tpm":200000},"anthropic/claude-3-5-haiku-20241022":{"rpm":50}}
+	var limits RateLimits
+	if raw := strings.TrimSpace(os.Getenv("SWARMONE_RATE_LIMITS")); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &limits); err != nil {
+			return nil, keys, fmt.Errorf("SWARMONE_RATE_LIMITS: %w", err)
+		}
+	}
+	limits, err = limits.normalized()
+	if err != nil {
+		return nil, keys, fmt.Errorf("SWARMONE_RATE_LIMITS: %w", err)
+	}
 
 	cfg := &Config{
 		Server: Server{
@@ -94,16 +388,102 @@ func Load() (*Config, Keys, error) {
 		},
 		Runners: runners,
 		Consensus: Consensus{
-			Judge: JudgeSpec{
-				Provider:  judgeProv,
-				Model:     judgeModel,
-				MaxTokens: judgeMax,
-			},
+			Mode:      consensus.Mode,
+			Normalize: consensus.Normalize,
+			Judge:     judge,
+			File:      consensus.File,
+		},
+		Breaker: BreakerSpec{
+			FailureThreshold: parseIntDefault(os.Getenv("BREAKER_FAILURE_THRESHOLD"), 5),
+			Cooldown:         parseDurDefault(os.Getenv("BREAKER_COOLDOWN"), 30*time.Second),
+			Window:           parseIntDefault(os.Getenv("BREAKER_WINDOW"), 20),
 		},
+		HTTP:       transport,
+		RateLimits: limits,
+		Embeddings: embeddings,
 	}
 	return cfg, keys, nil
 }
 
+// configFiles are tried in order when SWARMONE_CONFIG is unset: relative to
+// the working directory (backend/ or the repository root), then next to the
+// binary.
+func configFiles() []string {
+	files := []string{"config/config.yaml", "backend/config/config.yaml"}
+	if exe, err := os.Executable(); err == nil {
+		files = append(files, filepath.Join(filepath.Dir(exe), "config", "config.yaml"))
+	}
+	return files
+}
+
+// readConsensus reads consensus.mode and consensus.normalize from the YAML
+// config file at path (the first of configFiles that exists when empty) and
+// validates them. The mode defaults to ModeJudge; the other sections of the
+// file are not read.
+func readConsensus(path string) (Consensus, error) {
+	name := path
+	if name == "" {
+		for _, f := range configFiles() {
+			if _, err := os.Stat(f); err == nil {
+				name = f
+				break
+			}
+		}
+		if name == "" {
+			return Consensus{Mode: ModeJudge}, nil
+		}
+	}
+	raw, err := os.ReadFile(name)
+	if err != nil {
+		return Consensus{}, fmt.Errorf("config file: %w", err)
+	}
+	var file struct {
+		Consensus Consensus `yaml:"consensus"`
+	}
+	if err := yaml.Unmarshal(raw, &file); err != nil {
+		return Consensus{}, fmt.Errorf("%s: %w", name, err)
+	}
+
+	c := file.Consensus
+	c.Mode = strings.ToLower(strings.TrimSpace(firstNonEmpty(c.Mode, ModeJudge)))
+	if c.Mode != ModeJudge && c.Mode != ModeExact {
+		return Consensus{}, fmt.Errorf("%s: consensus.mode: unknown mode %q (want judge or exact)", name, c.Mode)
+	}
+	var normalize []string
+	for _, n := range c.Normalize {
+		if n = strings.ToLower(strings.TrimSpace(n)); n != "" {
+			normalize = append(normalize, n)
+		}
+	}
+	if err := validNormalize(normalize); err != nil {
+		return Consensus{}, fmt.Errorf("%s: consensus.normalize: %w", name, err)
+	}
+	c.Normalize = normalize
+	c.File = name
+	return c, nil
+}
+
+// judgeSampling pins an unset judge temperature to 0 so picks are
+// reproducible. Judges with reasoning settings, reasoning models in the
+// catalog (which reject or ignore temperature) and providers without a
+// temperature parameter keep the provider default.
+func judgeSampling(j JudgeSpec) provider.Sampling {
+	s := j.Sampling
+	if s.Temperature != nil || j.Reasoning != nil {
+		return s
+	}
+	if m, ok := provider.LookupModel(j.Provider, j.Model); ok && m.Reasoning {
+		return s
+	}
+	zero := 0.0
+	pinned := s
+	pinned.Temperature = &zero
+	if provider.ValidateSampling(j.Provider, pinned) != nil {
+		return s
+	}
+	return pinned
+}
+
 func parseDurDefault(s string, d time.Duration) time.Duration {
 	if strings.TrimSpace(s) == "" {
 		return d
@@ -0,0 +1,166 @@
+package orch
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/you/swarmone/internal/provider"
+)
+
+func TestReadConsensus(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	tests := []struct {
+		name      string
+		path      string
+		wantMode  string
+		wantNorm  []string
+		wantError string
+	}{
+		{
+			name:     "exact with normalization",
+			path:     write("exact.yaml", "consensus:\n  mode: \" Exact \"\n  normalize: [\"Whitespace\", \"case\", \"\"]\n  judge:\n    provider: ignored\n"),
+			wantMode: ModeExact, wantNorm: []string{NormWhitespace, NormCase},
+		},
+		{
+			name:     "no consensus section defaults to judge",
+			path:     write("empty.yaml", "server:\n  addr: \":8080\"\n"),
+			wantMode: ModeJudge,
+		},
+		{name: "unknown mode", path: write("mode.yaml", "consensus:\n  mode: vote\n"), wantError: "unknown mode"},
+		{name: "unknown normalization", path: write("norm.yaml", "consensus:\n  normalize: [stem]\n"), wantError: "unknown normalization"},
+		{name: "bad yaml", path: write("bad.yaml", "consensus: [\n"), wantError: "bad.yaml"},
+		{name: "missing default file is fine", path: "", wantMode: ModeJudge}, // tests run without config/config.yaml
+		{name: "explicit path must exist", path: filepath.Join(dir, "missing.yaml"), wantError: "config file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := readConsensus(tt.path)
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("readConsensus error = %v, want %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readConsensus: %v", err)
+			}
+			if c.Mode != tt.wantMode || !reflect.DeepEqual(c.Normalize, tt.wantNorm) {
+				t.Errorf("got mode %q normalize %v, want %q %v", c.Mode, c.Normalize, tt.wantMode, tt.wantNorm)
+			}
+			if c.Judge.Provider != "" {
+				t.Errorf("judge read from the file: %+v", c.Judge)
+			}
+			if c.File != tt.path {
+				t.Errorf("file = %q, want %q", c.File, tt.path)
+			}
+		})
+	}
+}
+
+// loadEnv points Load at test runners and an empty config file.
+func loadEnv(t *testing.T, runners, judge string) {
+	t.Helper()
+	cfgFile := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cfgFile, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SWARMONE_CONFIG", cfgFile)
+	t.Setenv("SWARMONE_RUNNERS", runners)
+	t.Setenv("SWARMONE_JUDGE", judge)
+	for _, k := range []string{"JUDGE_PROVIDER", "JUDGE_MODEL", "JUDGE_MAX_TOKENS", "JUDGE_BASE_URL", "SWARMONE_MODELS", "SWARMONE_EMBEDDINGS", "SWARMONE_HTTP", "SWARMONE_RATE_LIMITS"} {
+		t.Setenv(k, "")
+	}
+}
+
+func TestLoadChecksToolNames(t *testing.T) {
+	registerTestTool(t, "config_test_tool")
+	tests := []struct {
+		tools   string
+		wantErr string
+	}{
+		{tools: `["config_test_tool"]`},
+		{tools: `["*"]`},
+		{tools: `["config_test_tool","nope"]`, wantErr: `runner "r1": unknown tool "nope"`},
+	}
+	for _, tt := range tests {
+		loadEnv(t, `[{"name":"r1","provider":"mock","model":"m","max_tokens":64,"tools":`+tt.tools+`}]`, "")
+		_, _, err := Load()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("tools %s: Load: %v", tt.tools, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("tools %s: Load error = %v, want %q", tt.tools, err, tt.wantErr)
+		}
+	}
+}
+
+var testTools sync.Map // name → registered
+
+// registerTestTool registers an echo tool once per test binary.
+func registerTestTool(t *testing.T, name string) {
+	t.Helper()
+	if _, done := testTools.LoadOrStore(name, true); done {
+		return
+	}
+	err := RegisterTool(provider.Tool{Name: name, Description: "echoes its input", Parameters: json.RawMessage(`{"type":"object"}`)},
+		func(_ context.Context, args json.RawMessage) (string, error) {
+			return "echo " + strings.TrimSpace(string(args)), nil
+		})
+	if err != nil {
+		t.Fatalf("register %s: %v", name, err)
+	}
+}
+
+func TestJudgeSampling(t *testing.T) {
+	warm := 0.7
+	tests := []struct {
+		name string
+		j    JudgeSpec
+		want *float64
+	}{
+		{name: "anthropic default judge is pinned to 0", j: JudgeSpec{Provider: "anthropic", Model: "claude-3-5-sonnet-20241022"}, want: new(float64)},
+		{name: "unknown model is pinned", j: JudgeSpec{Provider: "ollama", Model: "llama3.1"}, want: new(float64)},
+		{name: "explicit temperature wins", j: JudgeSpec{Provider: "anthropic", Model: "claude-3-5-sonnet-20241022", Sampling: provider.Sampling{Temperature: &warm}}, want: &warm},
+		{name: "reasoning settings keep the default", j: JudgeSpec{Provider: "anthropic", Model: "claude-sonnet-4-5", Reasoning: &provider.Reasoning{Effort: "low"}}},
+		{name: "reasoning model keeps the default", j: JudgeSpec{Provider: "openai", Model: "gpt-5-mini"}},
+		{name: "provider without temperature", j: JudgeSpec{Provider: "http", Model: "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := judgeSampling(tt.j).Temperature
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("temperature = %v, want %v", ptrStr(got), ptrStr(tt.want))
+			}
+		})
+	}
+
+	loadEnv(t, "", "")
+	cfg, _, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tp := cfg.Consensus.Judge.Temperature; tp == nil || *tp != 0 {
+		t.Errorf("default judge temperature = %v, want 0", ptrStr(tp))
+	}
+}
+
+func ptrStr(p *float64) string {
+	if p == nil {
+		return "unset"
+	}
+	return strconv.FormatFloat(*p, 'g', -1, 64)
+}
@@ -0,0 +1,54 @@
+package orch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/you/swarmone/internal/provider"
+)
+
+// Embed returns one vector per text from the configured embedding model
+// (Config.Embeddings), honoring its rate limit and circuit breaker.
+func Embed(ctx context.Context, cfg *Config, keys Keys, texts []string) (provider.Embeddings, error) {
+	if cfg == nil {
+		return provider.Embeddings{}, errors.New("nil config")
+	}
+	if len(texts) == 0 {
+		return provider.Embeddings{}, nil
+	}
+	es := cfg.Embeddings
+	cl, err := buildClient(es, keys)
+	if err != nil {
+		return provider.Embeddings{}, fmt.Errorf("build embeddings client: %w", err)
+	}
+	em, ok := cl.(provider.Embedder)
+	if !ok {
+		return provider.Embeddings{}, fmt.Errorf("provider %q does not support embeddings", es.Provider)
+	}
+	// ectx bounds the call; ctx (the caller's) decides whether a failure
+	// was our own cancellation when it is recorded.
+	ectx := ctx
+	if cfg.Server.RunnerTimeout > 0 {
+		var cancel context.CancelFunc
+		ectx, cancel = context.WithTimeout(ctx, cfg.Server.RunnerTimeout)
+		defer cancel()
+	}
+
+	n := 0
+	for _, t := range texts {
+		n += provider.CountTokens(es.Provider, t)
+	}
+	if err := limiterFor(cfg.RateLimits, es.Provider, es.Model).wait(ectx, n); err != nil {
+		return provider.Embeddings{}, err
+	}
+	br := breakerFor(cfg.Breaker, es.Provider, es.Model)
+	if !br.allow(time.Now()) {
+		return provider.Embeddings{}, errCircuitOpen
+	}
+	start := time.Now()
+	out, err := em.Embed(ectx, texts)
+	br.record(ctx, err, time.Since(start), time.Now())
+	return out, err
+}
@@ -0,0 +1,63 @@
+package orch
+
+import (
+	"errors"
+
+	"github.com/you/swarmone/internal/provider"
+)
+
+// Error kinds produced by the orchestrator itself; provider failures use provider.ErrorKind values.
+const (
+	KindCircuitOpen = "circuit_open"
+	KindToolLoop    = "tool_loop"
+	KindRateLimited = "rate_limited_local" // no local rate limit capacity before the deadline
+)
+
+// RunnerError is the structured form of a runner failure in Meta.RunnerErrors.
+// Kind is stable and meant to be switched on (see provider.ErrorKind and the Kind* constants above).
+type RunnerError struct {
+	Kind      string `json:"kind"`
+	Message   string `json:"message"`
+	Provider  string `json:"provider,omitempty"`
+	Model     string `json:"model,omitempty"`
+	Status    int    `json:"status,omitempty"` // HTTP status from the provider
+	Code      string `json:"code,omitempty"`   // provider error code/type
+	Retryable bool   `json:"retryable"`
+}
+
+func (e *RunnerError) Error() string { return e.Message }
+
+// ErrorKind returns the kind RunnerErrors would report for err (one of the
+// Kind* constants above or a provider.ErrorKind), so callers such as the HTTP
+// layer can map failures of Embed and the judge to a status.
+func ErrorKind(err error) string {
+	if err == nil {
+		return ""
+	}
+	return newRunnerError(RunnerSpec{}, err).Kind
+}
+
+func newRunnerError(rs RunnerSpec, err error) *RunnerError {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, errCircuitOpen) {
+		return &RunnerError{Kind: KindCircuitOpen, Message: err.Error(), Provider: rs.Provider, Model: rs.Model, Retryable: true}
+	}
+	if errors.Is(err, errRateLimited) {
+		return &RunnerError{Kind: KindRateLimited, Message: err.Error(), Provider: rs.Provider, Model: rs.Model, Retryable: true}
+	}
+	if errors.Is(err, errToolLoop) {
+		return &RunnerError{Kind: KindToolLoop, Message: err.Error(), Provider: rs.Provider, Model: rs.Model}
+	}
+	pe := provider.AsError(err)
+	return &RunnerError{
+		Kind:      string(pe.Kind),
+		Message:   err.Error(),
+		Provider:  rs.Provider,
+		Model:     rs.Model,
+		Status:    pe.Status,
+		Code:      pe.Code,
+		Retryable: pe.Retryable,
+	}
+}
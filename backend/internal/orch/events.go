@@ -0,0 +1,71 @@
+package orch
+
+import "encoding/json"
+
+// Event types emitted by ExecuteStream.
+const (
+	EventRunnerStart = "runner_start"
+	EventRunnerDelta = "runner_delta"
+	EventRunnerTool  = "runner_tool"
+	EventRunnerDone  = "runner_done"
+	EventJudge       = "judge"
+	EventVote        = "vote"
+)
+
+// Event is a progress notification from ExecuteStream.
+// Data is one of the payload structs below; every payload carries the consensus_id.
+type Event struct {
+	Type string
+	Data any
+}
+
+// RunnerStart is sent when a runner's request is dispatched.
+type RunnerStart struct {
+	ConsensusID string `json:"consensus_id"`
+	Runner      int    `json:"runner"`
+	Name        string `json:"name"`
+	Provider    string `json:"provider"`
+	Model       string `json:"model"`
+}
+
+// RunnerDelta carries a streamed text fragment of one runner.
+type RunnerDelta struct {
+	ConsensusID string `json:"consensus_id"`
+	Runner      int    `json:"runner"`
+	Delta       string `json:"delta"`
+}
+
+// RunnerTool reports a tool call executed on behalf of a runner.
+type RunnerTool struct {
+	ConsensusID string          `json:"consensus_id"`
+	Runner      int             `json:"runner"`
+	CallID      string          `json:"call_id"`
+	Tool        string          `json:"tool"`
+	Arguments   json.RawMessage `json:"arguments"`
+	Output      string          `json:"output"`
+	IsError     bool            `json:"is_error,omitempty"`
+}
+
+// RunnerDone is sent once per runner when its answer (or error) is final.
+type RunnerDone struct {
+	ConsensusID string       `json:"consensus_id"`
+	Runner      int          `json:"runner"`
+	Name        string       `json:"name"`
+	Text        string       `json:"text,omitempty"`
+	Error       *RunnerError `json:"error,omitempty"`
+}
+
+// JudgeResult reports the judge's per-runner scores and winner.
+type JudgeResult struct {
+	ConsensusID string    `json:"consensus_id"`
+	WinnerIndex int       `json:"winner_index"`
+	Scores      []float64 `json:"scores"`
+}
+
+// VoteResult reports the plurality vote of exact mode, in place of JudgeResult.
+type VoteResult struct {
+	ConsensusID string    `json:"consensus_id"`
+	WinnerIndex int       `json:"winner_index"`
+	Votes       []int     `json:"votes_per_candidate"`
+	Scores      []float64 `json:"scores"`
+}
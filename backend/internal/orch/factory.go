@@ -1,23 +1,38 @@
 package orch
 
 import (
-	"fmt"
-	"strings"
-
 	"github.com/you/swarmone/internal/provider"
 )
 
-// buildClient creates a provider.Client from RunnerSpec + Keys.
-// Requires provider package to expose NewOpenAI / NewGemini / NewAnthropic.
+// buildClient creates a provider.Client from RunnerSpec + Keys through the
+// provider registry and applies the runner's retry policy.
 func buildClient(r RunnerSpec, keys Keys) (provider.Client, error) {
-	switch strings.ToLower(strings.TrimSpace(r.Provider)) {
-	case "openai":
-		return provider.NewOpenAI(r.Model, keys.OpenAI), nil
-	case "gemini", "google", "googleai":
-		return provider.NewGemini(r.Model, keys.Google), nil
-	case "anthropic", "claude":
-		return provider.NewAnthropic(r.Model, keys.Anthropic), nil
-	default:
-		return nil, fmt.Errorf("unknown provider %q", r.Provider)
+	cl, err := provider.Build(r.Provider, r.providerConfig(keys))
+	if err != nil {
+		return nil, err
+	}
+	if rs, ok := cl.(provider.RetrySetter); ok {
+		rs.SetRetryPolicy(r.Retry.policy())
+	}
+	return cl, nil
+}
+
+// providerConfig resolves the runner's settings for the provider factory.
+// The runner's own key wins over Keys; without either, the provider's
+// KeyEnv applies.
+func (r RunnerSpec) providerConfig(keys Keys) provider.Config {
+	return provider.Config{
+		Model:     r.Model,
+		APIKey:    firstNonEmpty(r.apiKey(), provider.Keys(keys).For(r.Provider)),
+		BaseURL:   r.BaseURL,
+		Headers:   r.Headers,
+		Options:   r.Options,
+		KeepAlive: r.KeepAlive,
+		Fixture:   r.Fixture,
+		Command:   r.Command,
+		Env:       r.Env,
+		Dir:       r.Dir,
+		Timeout:   parseDurDefault(r.Timeout, 0),
+		Template:  r.Template,
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -15,14 +16,48 @@ import (
 	"github.com/you/swarmone/internal/provider"
 )
 
-// Meta returned to HTTP layer (judge-only).
+// Meta returned to HTTP layer.
 type Meta struct {
-	WinnerIndex     int       `json:"winner_index"`
-	Runners         int       `json:"runners"`
-	Scores          []float64 `json:"scores"`
-	IncludedIndices []int     `json:"included_indices"`
-	ConsensusID     string    `json:"consensus_id"`
-	RunnerErrors    []string  `json:"runner_errors"`
+	WinnerIndex     int            `json:"winner_index"`
+	Runners         int            `json:"runners"`
+	Scores          []float64      `json:"scores"`
+	IncludedIndices []int          `json:"included_indices"`
+	ConsensusID     string         `json:"consensus_id"`
+	RunnerErrors    []*RunnerError `json:"runner_errors"` // index-aligned; nil for runners that succeeded
+
+	// VotesPerCandidate (exact mode) is index-aligned with runners: how many
+	// runners gave the same (normalized) answer as this one.
+	VotesPerCandidate []int `json:"votes_per_candidate,omitempty"`
+
+	RunnerCalls []CallMeta     `json:"runner_calls"`         // index-aligned with runners
+	JudgeCall   *CallMeta      `json:"judge_call,omitempty"` // nil when the judge was not reached
+	TotalUsage  provider.Usage `json:"total_usage"`          // runners + judge
+}
+
+// CallMeta records what one provider call reported back.
+type CallMeta struct {
+	Name         string         `json:"name"`
+	Provider     string         `json:"provider"`
+	Model        string         `json:"model"`
+	FinishReason string         `json:"finish_reason,omitempty"`
+	RequestID    string         `json:"request_id,omitempty"`
+	Usage        provider.Usage `json:"usage"`
+	Attempts     int            `json:"attempts"`
+	ToolCalls    int            `json:"tool_calls,omitempty"` // tools executed before the answer
+	Preflight    *Preflight     `json:"preflight,omitempty"`  // context window check; nil when the window is unknown
+	Repaired     bool           `json:"repaired,omitempty"`   // judge only: the reply was not valid JSON and was scraped
+}
+
+func newCallMeta(rs RunnerSpec, r provider.Result) CallMeta {
+	return CallMeta{
+		Name:         rs.Name,
+		Provider:     rs.Provider,
+		Model:        rs.Model,
+		FinishReason: r.FinishReason,
+		RequestID:    r.RequestID,
+		Usage:        r.Usage,
+		Attempts:     r.Attempts,
+	}
 }
 
 type cand struct {
@@ -30,8 +65,49 @@ type cand struct {
 	Text string
 }
 
-// Execute: fan-out to runners → judge-only → map scores back → return.
+// Query is one consensus request. Output, when set, asks every runner for a
+// JSON answer matching the schema; answers that are not valid JSON count as
+// runner failures. Attachments go with the instruction; runners whose
+// provider cannot take them are skipped with kind unsupported_input.
+type Query struct {
+	Instruction string
+	Output      *provider.OutputSchema
+	Attachments []provider.Attachment
+
+	// Context is long shared material (documents, a template preamble) sent
+	// ahead of the instruction as a cacheable system message, so repeated
+	// questions over it hit the provider's prompt cache.
+	Context string
+}
+
+// prompt builds the single-turn request of q.
+func (q Query) prompt(maxTokens int) provider.Request {
+	r := provider.Prompt(q.Instruction, maxTokens)
+	r.Messages[0].Attachments = q.Attachments
+	if q.Context != "" {
+		r.Messages = append([]provider.Message{q.contextMessage()}, r.Messages...)
+	}
+	return r
+}
+
+func (q Query) contextMessage() provider.Message {
+	return provider.Message{Role: provider.RoleSystem, Content: q.Context, Cache: true}
+}
+
+// Execute: fan-out to runners → judge (or majority vote) → map scores back → return.
 func Execute(ctx context.Context, cfg *Config, keys Keys, instruction string) (string, Meta, error) {
+	return ExecuteQuery(ctx, cfg, keys, Query{Instruction: instruction}, nil)
+}
+
+// ExecuteStream is Execute with progress reporting: emit (if non-nil) receives
+// runner start/delta/done events and the judge result. Runners whose client
+// implements provider.Streamer stream their tokens; emit is never called concurrently.
+func ExecuteStream(ctx context.Context, cfg *Config, keys Keys, instruction string, emit func(Event)) (string, Meta, error) {
+	return ExecuteQuery(ctx, cfg, keys, Query{Instruction: instruction}, emit)
+}
+
+// ExecuteQuery is ExecuteStream for a full Query.
+func ExecuteQuery(ctx context.Context, cfg *Config, keys Keys, q Query, emit func(Event)) (string, Meta, error) {
 	if cfg == nil {
 		return "", Meta{}, errors.New("nil config")
 	}
@@ -41,12 +117,27 @@ func Execute(ctx context.Context, cfg *Config, keys Keys, instruction string) (s
 
 	// Build clients
 	clients := make([]provider.Client, len(cfg.Runners))
+	runnerTools := make([][]provider.Tool, len(cfg.Runners))
 	for i, r := range cfg.Runners {
 		cl, err := buildClient(r, keys)
 		if err != nil {
 			return "", Meta{}, fmt.Errorf("build client for runner %d failed: %w", i, err)
 		}
 		clients[i] = cl
+		if runnerTools[i], err = toolsFor(r.Tools); err != nil {
+			return "", Meta{}, fmt.Errorf("runner %d: %w", i, err)
+		}
+	}
+
+	consID := randomID()
+	var emitMu sync.Mutex
+	send := func(typ string, data any) {
+		if emit == nil {
+			return
+		}
+		emitMu.Lock()
+		defer emitMu.Unlock()
+		emit(Event{Type: typ, Data: data})
 	}
 
 	type res struct {
@@ -55,13 +146,14 @@ func Execute(ctx context.Context, cfg *Config, keys Keys, instruction string) (s
 		err  error
 	}
 	answers := make([]string, len(cfg.Runners))
-	runnerErrs := make([]string, len(cfg.Runners))
+	runnerErrs := make([]*RunnerError, len(cfg.Runners))
+	calls := make([]CallMeta, len(cfg.Runners))
 	ch := make(chan res, len(cfg.Runners))
 
 	var wg sync.WaitGroup
 	for i, spec := range cfg.Runners {
 		wg.Add(1)
-		go func(idx int, rs RunnerSpec, cl provider.Client) {
+		go func(idx int, rs RunnerSpec, cl provider.Client, tools []provider.Tool) {
 			defer wg.Done()
 
 			rctx := ctx
@@ -70,12 +162,84 @@ func Execute(ctx context.Context, cfg *Config, keys Keys, instruction string) (s
 				rctx, cancel = context.WithTimeout(ctx, cfg.Server.RunnerTimeout)
 				defer cancel()
 			}
-			t, _, err := cl.Generate(rctx, instruction, rs.MaxTokens)
+			send(EventRunnerStart, RunnerStart{ConsensusID: consID, Runner: idx, Name: rs.Name, Provider: rs.Provider, Model: rs.Model})
+
+			preq := q.prompt(rs.MaxTokens)
+			preq.Tools = tools
+			preq.Output = q.Output
+			preq.Sampling = rs.Sampling
+			preq.Reasoning = rs.Reasoning
+			var err error
+			var pf *Preflight
+			orig := rs
+			if rs, preq, pf, err = preflight(rs, preq); err == nil && rs.Model != orig.Model {
+				cl, err = buildClient(rs, keys)
+			}
+			call := cl.Generate
+			if st, ok := cl.(provider.Streamer); ok && emit != nil {
+				call = func(ctx context.Context, r provider.Request) (provider.Result, error) {
+					return st.Stream(ctx, r, func(d string) {
+						send(EventRunnerDelta, RunnerDelta{ConsensusID: consID, Runner: idx, Delta: d})
+					})
+				}
+			}
+			var out provider.Result
+			var ntools int
+			br := breakerFor(cfg.Breaker, rs.Provider, rs.Model)
+			lim := limiterFor(cfg.RateLimits, rs.Provider, rs.Model)
+			if err != nil {
+				// Skipped by the context window check.
+			} else if err = provider.CheckAttachments(rs.Provider, preq); err != nil {
+				// Skipped: the provider cannot read the attachments.
+			} else if err = lim.wait(rctx, provider.EstimateTokens(rs.Provider, preq)); err != nil {
+				// No local capacity: the provider was never called.
+			} else if !br.allow(time.Now()) {
+				err = errCircuitOpen
+			} else {
+				start := time.Now()
+				first := true
+				out, ntools, err = converse(rctx, preq, rs.MaxToolRounds,
+					func(r provider.Request) (provider.Result, error) {
+						// The first round's capacity was reserved above.
+						if !first {
+							if err := lim.wait(rctx, provider.EstimateTokens(rs.Provider, r)); err != nil {
+								return provider.Result{}, err
+							}
+						}
+						first = false
+						return call(rctx, r)
+					},
+					func(tc provider.ToolCall, output string, isErr bool) {
+						send(EventRunnerTool, RunnerTool{ConsensusID: consID, Runner: idx, CallID: tc.ID, Tool: tc.Name, Arguments: tc.Arguments, Output: output, IsError: isErr})
+					})
+				// A tool loop is the runner's own fault; a local rate limit
+				// in a later round comes after calls that did succeed.
+				berr := err
+				if errors.Is(err, errToolLoop) || errors.Is(err, errRateLimited) {
+					berr = nil
+				}
+				br.record(ctx, berr, time.Since(start), time.Now())
+			}
+			if err == nil && q.Output != nil {
+				out.Text = stripCodeFence(out.Text)
+				if !json.Valid([]byte(out.Text)) {
+					err = &provider.Error{Provider: rs.Provider, Kind: provider.KindBadResponse, Message: "answer is not valid JSON"}
+				}
+			}
+			calls[idx] = newCallMeta(rs, out)
+			calls[idx].ToolCalls = ntools
+			calls[idx].Preflight = pf
+			t := strings.TrimSpace(out.Text)
+			done := RunnerDone{ConsensusID: consID, Runner: idx, Name: rs.Name}
 			if err != nil {
-				runnerErrs[idx] = err.Error()
+				runnerErrs[idx] = newRunnerError(rs, err)
+				done.Error = runnerErrs[idx]
+			} else {
+				done.Text = t
 			}
-			ch <- res{idx: idx, text: strings.TrimSpace(t), err: err}
-		}(i, spec, clients[i])
+			send(EventRunnerDone, done)
+			ch <- res{idx: idx, text: t, err: err}
+		}(i, spec, clients[i], runnerTools[i])
 	}
 
 	go func() { wg.Wait(); close(ch) }()
@@ -95,30 +259,44 @@ func Execute(ctx context.Context, cfg *Config, keys Keys, instruction string) (s
 		}
 	}
 
-	consID := randomID()
+	meta := Meta{
+		WinnerIndex:     -1,
+		Runners:         len(cfg.Runners),
+		Scores:          make([]float64, len(cfg.Runners)),
+		IncludedIndices: included,
+		ConsensusID:     consID,
+		RunnerErrors:    runnerErrs,
+		RunnerCalls:     calls,
+	}
+	for _, c := range calls {
+		meta.TotalUsage.Add(c.Usage)
+	}
 	if len(cands) == 0 {
-		meta := Meta{
-			WinnerIndex:     -1,
-			Runners:         len(cfg.Runners),
-			Scores:          make([]float64, len(cfg.Runners)),
-			IncludedIndices: included,
-			ConsensusID:     consID,
-			RunnerErrors:    runnerErrs,
-		}
 		return "", meta, fmt.Errorf("all runners failed")
 	}
 
+	// Exact mode: plurality vote, no judge call. Scores are vote shares.
+	if cfg.Consensus.Mode == ModeExact {
+		votes, winner := plurality(cands, len(cfg.Runners), cfg.Consensus.Normalize)
+		meta.VotesPerCandidate = votes
+		if winner < 0 {
+			return "", meta, fmt.Errorf("%w: the %d answers all differ", errNoConsensus, len(cands))
+		}
+		for i, v := range votes {
+			meta.Scores[i] = clampRound4(float64(v) / float64(len(cands)))
+		}
+		meta.WinnerIndex = winner
+		send(EventVote, VoteResult{ConsensusID: consID, WinnerIndex: winner, Votes: votes, Scores: meta.Scores})
+		return answers[winner], meta, nil
+	}
+
 	// Judge-only
-	winnerOrig, candScores, err := judgePick(ctx, cfg, keys, instruction, answers, cands)
+	winnerOrig, candScores, judgeCall, err := judgePick(ctx, cfg, keys, q, answers, cands)
+	if judgeCall != nil {
+		meta.JudgeCall = judgeCall
+		meta.TotalUsage.Add(judgeCall.Usage)
+	}
 	if err != nil {
-		meta := Meta{
-			WinnerIndex:     -1,
-			Runners:         len(cfg.Runners),
-			Scores:          make([]float64, len(cfg.Runners)),
-			IncludedIndices: included,
-			ConsensusID:     consID,
-			RunnerErrors:    runnerErrs,
-		}
 		return "", meta, fmt.Errorf("judge error: %w", err)
 	}
 
@@ -132,41 +310,66 @@ func Execute(ctx context.Context, cfg *Config, keys Keys, instruction string) (s
 			absScores[c.Orig] = clampRound4(candScores[i])
 		}
 	}
+	send(EventJudge, JudgeResult{ConsensusID: consID, WinnerIndex: winnerOrig, Scores: absScores})
 
-	meta := Meta{
-		WinnerIndex:     winnerOrig,
-		Runners:         len(cfg.Runners),
-		Scores:          absScores,
-		IncludedIndices: included,
-		ConsensusID:     consID,
-		RunnerErrors:    runnerErrs,
-	}
+	meta.WinnerIndex = winnerOrig
+	meta.Scores = absScores
 	return answers[winnerOrig], meta, nil
 }
 
+// judgeRubric is the judge's system prompt; the user turn carries the
+// instruction and candidates as JSON.
+const judgeRubric = `You are a strict impartial judge.
+Score every candidate between 0 and 1 (4 decimals). Higher is better.
+Choose ONE winner. Return ONLY JSON as specified.
+
+Criteria:
+- Task match / completeness
+- Clarity / organization
+- Factuality / safety
+- Tone / style follows Language
+
+Schema:
+- scores: array of numbers in [0,1] with 4 decimals, length == number of candidates
+- winner: integer candidate index
+
+Return ONLY JSON: {"scores":[...], "winner": <int>}`
+
+// judgeOutput constrains the judge's reply to the rubric's JSON shape.
+var judgeOutput = &provider.OutputSchema{
+	Name:   "judge_scores",
+	Strict: true,
+	Schema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "scores": {"type": "array", "items": {"type": "number"}},
+    "winner": {"type": "integer"}
+  },
+  "required": ["scores", "winner"],
+  "additionalProperties": false
+}`),
+}
+
 // judgePick asks the judge model to score each candidate ([0,1], 4 decimals) and pick a winner.
-// NOTE: it now accepts []cand to match call-site type exactly.
+// The reply is schema-constrained (judgeOutput) where the provider supports
+// it; a reply that does not decode is scraped for numbers and the judge call
+// is marked Repaired.
 func judgePick(
 	ctx context.Context,
 	cfg *Config,
 	keys Keys,
-	instruction string,
+	q Query,
 	answers []string,
 	cands []cand,
-) (int, []float64, error) {
+) (int, []float64, *CallMeta, error) {
 	// Build judge client
 	if cfg.Consensus.Judge.Provider == "" || cfg.Consensus.Judge.Model == "" {
-		return 0, nil, errors.New("judge provider/model not configured")
-	}
-	jSpec := RunnerSpec{
-		Name:      "judge",
-		Provider:  cfg.Consensus.Judge.Provider,
-		Model:     cfg.Consensus.Judge.Model,
-		MaxTokens: cfg.Consensus.Judge.MaxTokens,
+		return 0, nil, nil, errors.New("judge provider/model not configured")
 	}
+	jSpec := cfg.Consensus.Judge.runnerSpec()
 	jc, err := buildClient(jSpec, keys)
 	if err != nil {
-		return 0, nil, fmt.Errorf("build judge client: %w", err)
+		return 0, nil, nil, fmt.Errorf("build judge client: %w", err)
 	}
 
 	// Prepare JSON payload for judge
@@ -180,24 +383,10 @@ func judgePick(
 	}
 	req := map[string]any{
 		"task":        "score each candidate and choose a single best one",
-		"instruction": instruction,
+		"instruction": q.Instruction,
 		"candidates":  jcands,
-		"schema": map[string]any{
-			"scores": "array of numbers in [0,1] with 4 decimals, length == number of candidates",
-			"winner": "integer candidate index",
-		},
-		"criteria": []string{
-			"Task match / completeness",
-			"Clarity / organization",
-			"Factuality / safety",
-			"Tone / style follows Language",
-		},
-		"format": "Return ONLY JSON: {\"scores\":[...], \"winner\": <int>}",
 	}
 	b, _ := json.Marshal(req)
-	prompt := "You are a strict impartial judge.\n" +
-		"Score every candidate between 0 and 1 (4 decimals). Higher is better.\n" +
-		"Choose ONE winner. Return ONLY JSON as specified.\n\n" + string(b)
 
 	// Dedicated timeout for judge
 	var jctx context.Context = ctx
@@ -225,50 +414,84 @@ func judgePick(
 	if maxTok <= 0 {
 		maxTok = 256
 	}
-	out, _, err := jc.Generate(jctx, prompt, maxTok)
+	// The rubric (and the shared context, if any) is the same on every
+	// call, so it is marked as a cacheable prefix.
+	msgs := []provider.Message{{Role: provider.RoleSystem, Content: judgeRubric, Cache: true}}
+	if q.Context != "" {
+		msgs = append(msgs, q.contextMessage())
+	}
+	msgs = append(msgs, provider.Message{Role: provider.RoleUser, Content: string(b)})
+	jreq := provider.Request{
+		Messages:  msgs,
+		MaxTokens: maxTok,
+		Output:    judgeOutput,
+		Sampling:  jSpec.Sampling,
+		Reasoning: jSpec.Reasoning,
+		CacheKey:  "swarmone-judge",
+	}
+	// The judge sees the attachments too when its provider can read them.
+	if len(q.Attachments) > 0 {
+		last := &jreq.Messages[len(jreq.Messages)-1]
+		last.Attachments = q.Attachments
+		if provider.CheckAttachments(jSpec.Provider, jreq) != nil {
+			last.Attachments = nil
+		}
+	}
+	// Wait for capacity first: allow may claim the half-open probe, which
+	// only record releases.
+	if err := limiterFor(cfg.RateLimits, jSpec.Provider, jSpec.Model).wait(jctx, provider.EstimateTokens(jSpec.Provider, jreq)); err != nil {
+		return 0, nil, nil, err
+	}
+	br := breakerFor(cfg.Breaker, jSpec.Provider, jSpec.Model)
+	if !br.allow(time.Now()) {
+		return 0, nil, nil, errCircuitOpen
+	}
+	start := time.Now()
+	out, err := jc.Generate(jctx, jreq)
+	br.record(ctx, err, time.Since(start), time.Now())
+	call := newCallMeta(jSpec, out)
 	if err != nil {
-		return 0, nil, err
+		return 0, nil, &call, err
 	}
-	txt := strings.TrimSpace(out)
+	txt := strings.TrimSpace(out.Text)
 	if txt == "" {
-		return 0, nil, errors.New("judge returned empty content")
+		return 0, nil, &call, errors.New("judge returned empty content")
 	}
 	txt = stripCodeFence(txt)
 
-	// Parse/repair JSON
 	var jr struct {
 		Scores []float64 `json:"scores"`
 		Winner *int      `json:"winner"`
 	}
 	if err := json.Unmarshal([]byte(txt), &jr); err != nil {
-		nums := extractNumbers(txt)
-		if len(nums) >= len(cands) {
+		// Providers without schema enforcement (no StructuredOutput
+		// capability) may answer in prose: scrape the scores and winner.
+		if nums := extractNumbers(winnerRe.ReplaceAllString(txt, " ")); len(nums) >= len(cands) {
 			jr.Scores = nums[:len(cands)]
 		}
-		if jr.Winner == nil {
-			if w := findWinnerIndex(txt); w >= 0 && w < len(cands) {
-				jr.Winner = &w
-			}
+		if w := findWinnerIndex(txt); w >= 0 {
+			jr.Winner = &w
 		}
 		if len(jr.Scores) != len(cands) || jr.Winner == nil {
-			return 0, nil, fmt.Errorf("judge unparsable: %s", truncate(txt, 500))
+			return 0, nil, &call, fmt.Errorf("judge reply unparsable: %v: %s", err, truncate(txt, 500))
 		}
+		call.Repaired = true
+	}
+	if jr.Winner == nil {
+		return 0, nil, &call, fmt.Errorf("judge reply has no winner: %s", truncate(txt, 500))
 	}
 	if len(jr.Scores) != len(cands) {
-		return 0, nil, fmt.Errorf("judge scores length mismatch: got %d, want %d", len(jr.Scores), len(cands))
+		return 0, nil, &call, fmt.Errorf("judge scores length mismatch: got %d, want %d", len(jr.Scores), len(cands))
 	}
 
-	w := 0
-	if jr.Winner != nil {
-		w = *jr.Winner
-	}
+	w := *jr.Winner
 	if w < 0 || w >= len(cands) {
 		w = argmax(jr.Scores)
 	}
 	for i := range jr.Scores {
 		jr.Scores[i] = clampRound4(jr.Scores[i])
 	}
-	return cands[w].Orig, jr.Scores, nil
+	return cands[w].Orig, jr.Scores, &call, nil
 }
 
 // -------- helpers --------
@@ -308,22 +531,20 @@ func extractNumbers(s string) []float64 {
 	m := numRe.FindAllString(s, -1)
 	out := make([]float64, 0, len(m))
 	for _, mm := range m {
-		var v float64
-		_, err := fmt.Sscanf(mm, "%f", &v)
-		if err == nil {
+		if v, err := strconv.ParseFloat(mm, 64); err == nil {
 			out = append(out, v)
 		}
 	}
 	return out
 }
 
+// winnerRe matches `"winner": 1` as well as prose such as "Winner: 1" or
+// "winner is candidate 1".
+var winnerRe = regexp.MustCompile(`(?i)"?winner"?\s*(?:[:=]|is)?\s*(?:candidate\s*|index\s*)?#?(\d+)`)
+
 func findWinnerIndex(s string) int {
-	re := regexp.MustCompile(`"winner"\s*:\s*(\d+)`)
-	ms := re.FindStringSubmatch(s)
-	if len(ms) == 2 {
-		var w int
-		_, err := fmt.Sscanf(ms[1], "%d", &w)
-		if err == nil {
+	if ms := winnerRe.FindStringSubmatch(s); len(ms) == 2 {
+		if w, err := strconv.Atoi(ms[1]); err == nil {
 			return w
 		}
 	}
@@ -0,0 +1,247 @@
+package orch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// e2eFixture scripts the runners and the judge for the end-to-end tests.
+// The judge is told apart by its system prompt; runners by model name.
+const e2eFixture = `{
+  "rules": [
+    {"match": {"system": "strict impartial judge", "contains": ["judge-invalid"]}, "respond": {"text": "winner is probably 1"}},
+    {"match": {"system": "strict impartial judge", "contains": ["judge-fenced"]}, "respond": {"text": "` + "```" + `json\n{\"scores\": [0.6, 0.3], \"winner\": 0}\n` + "```" + `"}},
+    {"match": {"system": "strict impartial judge", "contains": ["judge-prose"]}, "respond": {"text": "Winner: 0. Scores: 0.55 for the first, 0.25 for the second."}},
+    {"match": {"system": "strict impartial judge", "regex": "\"index\":2"}, "respond": {"json": {"scores": [0.2, 0.9, 0.41234], "winner": 1}}},
+    {"match": {"system": "strict impartial judge"}, "respond": {"json": {"scores": [0.7, 0.85], "winner": 1}}},
+    {"match": {"model": "e2e-fail"}, "respond": {"error": {"kind": "server", "status": 503, "code": "unavailable", "message": "scripted outage"}}},
+    {"match": {"model": "e2e-a"}, "respond": {"text": "Answer A.", "usage": {"input_tokens": 10, "output_tokens": 3}}},
+    {"match": {"model": "e2e-a-lower"}, "respond": {"text": "answer a", "usage": {"input_tokens": 10, "output_tokens": 2}}},
+    {"match": {"model": "e2e-b"}, "respond": {"text": "Answer B.", "usage": {"input_tokens": 10, "output_tokens": 3}}},
+    {"match": {"model": "e2e-b-lower"}, "respond": {"text": "answer b"}},
+    {"match": {"model": "e2e-c"}, "respond": {"text": "Answer C."}}
+  ]
+}`
+
+func TestExecuteWithMockProvider(t *testing.T) {
+	fixture := filepath.Join(t.TempDir(), "fixture.json")
+	if err := os.WriteFile(fixture, []byte(e2eFixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runner := func(model string) RunnerSpec {
+		return RunnerSpec{Name: model, Provider: "mock", Model: model, MaxTokens: 64, Fixture: fixture}
+	}
+	judge := JudgeSpec{Provider: "mock", Model: "e2e-judge", MaxTokens: 128, Fixture: fixture}
+
+	tests := []struct {
+		name        string
+		runners     []string
+		mode        string
+		normalize   []string
+		instruction string
+
+		wantAnswer   string
+		wantWinner   int
+		wantScores   []float64
+		wantVotes    []int
+		wantIncluded []int
+		wantJudge    bool     // the judge was called
+		wantRepaired bool     // the judge reply was scraped
+		wantErrKinds []string // index-aligned RunnerErrors kinds; "" = success
+		wantErr      string
+	}{
+		{
+			name:         "judge picks the second answer",
+			runners:      []string{"e2e-a", "e2e-b"},
+			instruction:  "Say something.",
+			wantAnswer:   "Answer B.",
+			wantWinner:   1,
+			wantScores:   []float64{0.7, 0.85},
+			wantIncluded: []int{0, 1},
+			wantJudge:    true,
+			wantErrKinds: []string{"", ""},
+		},
+		{
+			name:         "judge scores map back past a failed runner",
+			runners:      []string{"e2e-a", "e2e-fail", "e2e-b"},
+			instruction:  "Say something.",
+			wantAnswer:   "Answer B.",
+			wantWinner:   2,
+			wantScores:   []float64{0.7, 0, 0.85},
+			wantIncluded: []int{0, 2},
+			wantJudge:    true,
+			wantErrKinds: []string{"", "server", ""},
+		},
+		{
+			name:         "three candidates",
+			runners:      []string{"e2e-a", "e2e-b", "e2e-c"},
+			instruction:  "Say something.",
+			wantAnswer:   "Answer B.",
+			wantWinner:   1,
+			wantScores:   []float64{0.2, 0.9, 0.4123},
+			wantIncluded: []int{0, 1, 2},
+			wantJudge:    true,
+			wantErrKinds: []string{"", "", ""},
+		},
+		{
+			name:         "fenced judge JSON decodes",
+			runners:      []string{"e2e-a", "e2e-b"},
+			instruction:  "judge-fenced",
+			wantAnswer:   "Answer A.",
+			wantWinner:   0,
+			wantScores:   []float64{0.6, 0.3},
+			wantIncluded: []int{0, 1},
+			wantJudge:    true,
+			wantErrKinds: []string{"", ""},
+		},
+		{
+			name:         "prose judge reply is repaired",
+			runners:      []string{"e2e-a", "e2e-b"},
+			instruction:  "judge-prose",
+			wantAnswer:   "Answer A.",
+			wantWinner:   0,
+			wantScores:   []float64{0.55, 0.25},
+			wantIncluded: []int{0, 1},
+			wantJudge:    true,
+			wantRepaired: true,
+			wantErrKinds: []string{"", ""},
+		},
+		{
+			name:         "unparsable judge reply is an error",
+			runners:      []string{"e2e-a", "e2e-b"},
+			instruction:  "judge-invalid",
+			wantIncluded: []int{0, 1},
+			wantJudge:    true,
+			wantErrKinds: []string{"", ""},
+			wantErr:      "judge reply unparsable",
+		},
+		{
+			name:         "all runners failed",
+			runners:      []string{"e2e-fail"},
+			instruction:  "Say something.",
+			wantErrKinds: []string{"server"},
+			wantErr:      "all runners failed",
+		},
+		{
+			name:         "exact mode majority wins without the judge",
+			runners:      []string{"e2e-b", "e2e-a", "e2e-a-lower"},
+			mode:         ModeExact,
+			normalize:    []string{NormCase, NormPunctuation},
+			instruction:  "judge-invalid", // would fail if the judge were called
+			wantAnswer:   "Answer A.",
+			wantWinner:   1,
+			wantScores:   []float64{0.3333, 0.6667, 0.6667},
+			wantVotes:    []int{1, 2, 2},
+			wantIncluded: []int{0, 1, 2},
+			wantErrKinds: []string{"", "", ""},
+		},
+		{
+			name:         "exact mode tie goes to the lowest index",
+			runners:      []string{"e2e-b", "e2e-a", "e2e-a-lower", "e2e-b-lower"},
+			mode:         ModeExact,
+			normalize:    []string{NormCase, NormPunctuation},
+			instruction:  "judge-invalid",
+			wantAnswer:   "Answer B.",
+			wantWinner:   0,
+			wantScores:   []float64{0.5, 0.5, 0.5, 0.5},
+			wantVotes:    []int{2, 2, 2, 2},
+			wantIncluded: []int{0, 1, 2, 3},
+			wantErrKinds: []string{"", "", "", ""},
+		},
+		{
+			name:         "exact mode never calls the judge",
+			runners:      []string{"e2e-a", "e2e-b"},
+			mode:         ModeExact,
+			instruction:  "Say something.",
+			wantVotes:    []int{1, 1},
+			wantIncluded: []int{0, 1},
+			wantErrKinds: []string{"", ""},
+			wantErr:      "no consensus",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Consensus: Consensus{Mode: tt.mode, Normalize: tt.normalize, Judge: judge}}
+			for _, m := range tt.runners {
+				cfg.Runners = append(cfg.Runners, runner(m))
+			}
+			var events []string
+			answer, meta, err := ExecuteStream(context.Background(), cfg, Keys{}, tt.instruction, func(ev Event) {
+				events = append(events, ev.Type)
+			})
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if answer != tt.wantAnswer {
+				t.Errorf("answer = %q, want %q", answer, tt.wantAnswer)
+			}
+			if tt.wantErr == "" {
+				if meta.WinnerIndex != tt.wantWinner || !reflect.DeepEqual(meta.Scores, tt.wantScores) {
+					t.Errorf("winner %d scores %v, want %d %v", meta.WinnerIndex, meta.Scores, tt.wantWinner, tt.wantScores)
+				}
+			}
+			if !reflect.DeepEqual(meta.VotesPerCandidate, tt.wantVotes) {
+				t.Errorf("votes = %v, want %v", meta.VotesPerCandidate, tt.wantVotes)
+			}
+			if !reflect.DeepEqual(meta.IncludedIndices, tt.wantIncluded) {
+				t.Errorf("included = %v, want %v", meta.IncludedIndices, tt.wantIncluded)
+			}
+			if (meta.JudgeCall != nil) != tt.wantJudge {
+				t.Errorf("judge call = %+v, want called %v", meta.JudgeCall, tt.wantJudge)
+			}
+			if meta.JudgeCall != nil && meta.JudgeCall.Repaired != tt.wantRepaired {
+				t.Errorf("judge call repaired = %v, want %v", meta.JudgeCall.Repaired, tt.wantRepaired)
+			}
+			if meta.Runners != len(tt.runners) || len(meta.RunnerCalls) != len(tt.runners) {
+				t.Errorf("runners %d, calls %d, want %d", meta.Runners, len(meta.RunnerCalls), len(tt.runners))
+			}
+			for i, want := range tt.wantErrKinds {
+				got := ""
+				if e := meta.RunnerErrors[i]; e != nil {
+					got = e.Kind
+				}
+				if got != want {
+					t.Errorf("runner %d error kind = %q, want %q", i, got, want)
+				}
+			}
+
+			var sum int
+			for _, c := range meta.RunnerCalls {
+				sum += c.Usage.InputTokens
+			}
+			if meta.JudgeCall != nil {
+				sum += meta.JudgeCall.Usage.InputTokens
+			}
+			if meta.TotalUsage.InputTokens != sum {
+				t.Errorf("total input tokens = %d, want %d", meta.TotalUsage.InputTokens, sum)
+			}
+
+			// One start and one done per runner, then the judge or the vote.
+			count := map[string]int{}
+			for _, e := range events {
+				count[e]++
+			}
+			if count[EventRunnerStart] != len(tt.runners) || count[EventRunnerDone] != len(tt.runners) {
+				t.Errorf("events = %v", events)
+			}
+			if tt.wantErr == "" {
+				want := EventVote
+				if tt.wantJudge {
+					want = EventJudge
+				}
+				if last := events[len(events)-1]; last != want {
+					t.Errorf("last event = %q, want %q", last, want)
+				}
+			}
+		})
+	}
+}
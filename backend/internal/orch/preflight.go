@@ -0,0 +1,166 @@
+package orch
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/you/swarmone/internal/provider"
+)
+
+// Overflow policies: what a runner does when the prompt plus its output
+// budget does not fit the model's context window (RunnerSpec.Overflow).
+const (
+	OverflowSkip     = "skip"            // fail with kind context_length, without calling (default)
+	OverflowTruncate = "truncate_middle" // cut the middle of the longest message
+	OverflowEscalate = "escalate"        // switch to OverflowModel, or the catalog's next larger model
+	OverflowNone     = "none"            // send anyway; the provider decides
+)
+
+// Preflight records the context window check made before a runner's first
+// call (CallMeta.Preflight). Token counts are local estimates.
+type Preflight struct {
+	InputTokens     int    `json:"input_tokens"`
+	OutputBudget    int    `json:"output_budget"`
+	ContextWindow   int    `json:"context_window"`
+	Action          string `json:"action"` // "fits" or the overflow policy applied
+	TruncatedTokens int    `json:"truncated_tokens,omitempty"`
+	EscalatedFrom   string `json:"escalated_from,omitempty"` // the configured model
+}
+
+func validOverflow(p string) bool {
+	switch p {
+	case "", OverflowSkip, OverflowTruncate, OverflowEscalate, OverflowNone:
+		return true
+	}
+	return false
+}
+
+// contextWindow is the runner's own context_window, else the catalog's; 0
+// when unknown.
+func (r RunnerSpec) contextWindow() int {
+	if r.ContextWindow > 0 {
+		return r.ContextWindow
+	}
+	if m, ok := provider.LookupModel(r.Provider, r.Model); ok {
+		return m.ContextWindow
+	}
+	return 0
+}
+
+// preflight estimates req against rs's context window and applies the
+// overflow policy. It returns the spec and request to run (the model may be
+// escalated, the prompt truncated) and nil meta when the window is unknown.
+// An error means the runner is skipped.
+func preflight(rs RunnerSpec, req provider.Request) (RunnerSpec, provider.Request, *Preflight, error) {
+	window := rs.contextWindow()
+	if window <= 0 || rs.Overflow == OverflowNone {
+		return rs, req, nil, nil
+	}
+	pf := &Preflight{
+		InputTokens:   provider.EstimateInputTokens(rs.Provider, req),
+		OutputBudget:  req.OutputBudget(),
+		ContextWindow: window,
+		Action:        "fits",
+	}
+	if pf.InputTokens+pf.OutputBudget <= window {
+		return rs, req, pf, nil
+	}
+	overflow := func(format string, args ...any) error {
+		msg := fmt.Sprintf("prompt ~%d tokens + %d output exceed the %d-token context window", pf.InputTokens, pf.OutputBudget, pf.ContextWindow)
+		return &provider.Error{Provider: rs.Provider, Kind: provider.KindContextLength, Message: msg + fmt.Sprintf(format, args...)}
+	}
+
+	switch rs.Overflow {
+	case OverflowTruncate:
+		pf.Action = OverflowTruncate
+		i := longestMessage(req)
+		if i < 0 {
+			return rs, req, pf, overflow("; nothing to truncate")
+		}
+		m := req.Messages[i]
+		own := provider.CountTokens(rs.Provider, m.Content)
+		// Aim 5% under the window to absorb estimation error.
+		room := int(float64(window)*0.95) - pf.OutputBudget - (pf.InputTokens - own)
+		if room <= 0 {
+			return rs, req, pf, overflow("; the rest of the prompt leaves no room")
+		}
+		msgs := append([]provider.Message(nil), req.Messages...)
+		msgs[i].Content, pf.TruncatedTokens = provider.TruncateMiddle(rs.Provider, m.Content, room)
+		req.Messages = msgs
+		return rs, req, pf, nil
+
+	case OverflowEscalate:
+		pf.Action = OverflowEscalate
+		need := pf.InputTokens + pf.OutputBudget
+		target := rs.OverflowModel
+		if target == "" {
+			m, ok := largerModel(rs, need)
+			if !ok {
+				return rs, req, pf, overflow("; no larger %s model in the catalog", rs.Provider)
+			}
+			target = m.Model
+		}
+		esc := rs
+		esc.Model = target
+		esc.ContextWindow = 0
+		if w := esc.contextWindow(); w > 0 && need > w {
+			return rs, req, pf, overflow(", and so does %s (%d)", target, w)
+		}
+		pf.EscalatedFrom = rs.Model
+		return esc, req, pf, nil
+
+	default:
+		pf.Action = OverflowSkip
+		return rs, req, pf, overflow("; skipped")
+	}
+}
+
+// longestMessage is the index of the system or user message with the most
+// text, or -1.
+func longestMessage(r provider.Request) int {
+	best := -1
+	for i, m := range r.Messages {
+		if m.Role != provider.RoleSystem && m.Role != provider.RoleUser {
+			continue
+		}
+		if best < 0 || len(m.Content) > len(r.Messages[best].Content) {
+			best = i
+		}
+	}
+	if best >= 0 && r.Messages[best].Content == "" {
+		return -1
+	}
+	return best
+}
+
+// largerModel picks the catalog model of rs's provider that fits need tokens
+// and rs's answer budget: the smallest window, then the cheapest.
+func largerModel(rs RunnerSpec, need int) (provider.ModelInfo, bool) {
+	spec, ok := provider.Lookup(rs.Provider)
+	if !ok {
+		return provider.ModelInfo{}, false
+	}
+	var fits []provider.ModelInfo
+	for _, m := range provider.Models() {
+		if m.Provider != spec.Name || m.Model == rs.Model || m.ContextWindow < need {
+			continue
+		}
+		if m.MaxOutputTokens > 0 && m.MaxOutputTokens < rs.MaxTokens {
+			continue
+		}
+		if len(rs.Tools) > 0 && !m.Tools {
+			continue
+		}
+		fits = append(fits, m)
+	}
+	if len(fits) == 0 {
+		return provider.ModelInfo{}, false
+	}
+	sort.Slice(fits, func(i, j int) bool {
+		if fits[i].ContextWindow != fits[j].ContextWindow {
+			return fits[i].ContextWindow < fits[j].ContextWindow
+		}
+		return fits[i].InputPerMTok < fits[j].InputPerMTok
+	})
+	return fits[0], true
+}
@@ -0,0 +1,111 @@
+package orch
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/you/swarmone/internal/provider"
+)
+
+func TestPreflight(t *testing.T) {
+	long := strings.Repeat("lorem ipsum dolor sit amet. ", 100) // ~700 tokens
+	short := "What is two plus two?"
+
+	tests := []struct {
+		name      string
+		rs        RunnerSpec
+		prompt    string
+		wantPF    bool   // a Preflight is returned
+		action    string // Preflight.Action
+		wantErr   bool   // skipped with kind context_length
+		wantModel string // model to run; "" = unchanged
+	}{
+		{
+			name:   "unknown window is not checked",
+			rs:     RunnerSpec{Provider: "mock", Model: "m", MaxTokens: 100},
+			prompt: long,
+		},
+		{
+			name:   "none sends anyway",
+			rs:     RunnerSpec{Provider: "mock", Model: "m", MaxTokens: 100, ContextWindow: 200, Overflow: OverflowNone},
+			prompt: long,
+		},
+		{
+			name:   "fits",
+			rs:     RunnerSpec{Provider: "mock", Model: "m", MaxTokens: 100, ContextWindow: 200},
+			prompt: short, wantPF: true, action: "fits",
+		},
+		{
+			name:   "skip is the default",
+			rs:     RunnerSpec{Provider: "mock", Model: "m", MaxTokens: 100, ContextWindow: 200},
+			prompt: long, wantPF: true, action: OverflowSkip, wantErr: true,
+		},
+		{
+			name:   "truncate_middle",
+			rs:     RunnerSpec{Provider: "mock", Model: "m", MaxTokens: 100, ContextWindow: 300, Overflow: OverflowTruncate},
+			prompt: long, wantPF: true, action: OverflowTruncate,
+		},
+		{
+			name:   "truncate_middle without room for input",
+			rs:     RunnerSpec{Provider: "mock", Model: "m", MaxTokens: 290, ContextWindow: 300, Overflow: OverflowTruncate},
+			prompt: long, wantPF: true, action: OverflowTruncate, wantErr: true,
+		},
+		{
+			name:   "escalate to the next catalog model",
+			rs:     RunnerSpec{Provider: "anthropic", Model: "claude-3-5-haiku", MaxTokens: 100, ContextWindow: 300, Overflow: OverflowEscalate},
+			prompt: long, wantPF: true, action: OverflowEscalate, wantModel: "claude-haiku-4-5",
+		},
+		{
+			name:   "escalate to overflow_model",
+			rs:     RunnerSpec{Provider: "anthropic", Model: "claude-3-5-haiku", MaxTokens: 100, ContextWindow: 300, Overflow: OverflowEscalate, OverflowModel: "claude-sonnet-4-5"},
+			prompt: long, wantPF: true, action: OverflowEscalate, wantModel: "claude-sonnet-4-5",
+		},
+		{
+			name:   "escalate without a larger model",
+			rs:     RunnerSpec{Provider: "mock", Model: "m", MaxTokens: 100, ContextWindow: 300, Overflow: OverflowEscalate},
+			prompt: long, wantPF: true, action: OverflowEscalate, wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := provider.Prompt(tt.prompt, tt.rs.MaxTokens)
+			rs, out, pf, err := preflight(tt.rs, req)
+			if tt.wantErr {
+				if !errors.Is(err, provider.ErrContextLength) {
+					t.Fatalf("err = %v, want a context_length error", err)
+				}
+			} else if err != nil {
+				t.Fatalf("preflight: %v", err)
+			}
+			if (pf != nil) != tt.wantPF {
+				t.Fatalf("preflight meta = %+v, want present %v", pf, tt.wantPF)
+			}
+			if pf != nil && pf.Action != tt.action {
+				t.Errorf("Action = %q, want %q", pf.Action, tt.action)
+			}
+			wantModel := tt.rs.Model
+			if tt.wantModel != "" && !tt.wantErr {
+				wantModel = tt.wantModel
+				if pf.EscalatedFrom != tt.rs.Model {
+					t.Errorf("EscalatedFrom = %q, want %q", pf.EscalatedFrom, tt.rs.Model)
+				}
+			}
+			if rs.Model != wantModel {
+				t.Errorf("model = %q, want %q", rs.Model, wantModel)
+			}
+			if tt.action == OverflowTruncate && !tt.wantErr {
+				text := out.Messages[0].Content
+				if !strings.Contains(text, "[... truncated ...]") || pf.TruncatedTokens <= 0 {
+					t.Errorf("prompt not truncated: %d tokens removed, %q", pf.TruncatedTokens, text)
+				}
+				if n := provider.EstimateTokens(rs.Provider, out); n > tt.rs.ContextWindow {
+					t.Errorf("truncated request estimates %d tokens, over the %d window", n, tt.rs.ContextWindow)
+				}
+				if req.Messages[0].Content != tt.prompt {
+					t.Error("the caller's request was modified")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package orch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/you/swarmone/internal/provider"
+)
+
+// errRateLimited is reported for calls that could not get local rate limit
+// capacity before their deadline.
+var errRateLimited = errors.New("rate limited locally")
+
+// RateLimitSpec caps outbound calls to a provider or model: requests and
+// estimated tokens (prompt estimate plus the full output budget) per minute.
+// Zero means unlimited.
+type RateLimitSpec struct {
+	RPM int `json:"rpm"`
+	TPM int `json:"tpm"`
+}
+
+// RateLimits maps "provider" or "provider/model" to a limit; a model entry
+// wins over its provider's. Keys use canonical provider names (see Load).
+type RateLimits map[string]RateLimitSpec
+
+// lookup returns the key and spec governing provider/model.
+func (rl RateLimits) lookup(providerName, model string) (string, RateLimitSpec, bool) {
+	p := canonicalProvider(providerName)
+	if s, ok := rl[p+"/"+model]; ok {
+		return p + "/" + model, s, true
+	}
+	if s, ok := rl[p]; ok {
+		return p, s, true
+	}
+	return "", RateLimitSpec{}, false
+}
+
+// normalized validates the limits and rewrites provider aliases to their
+// canonical names.
+func (rl RateLimits) normalized() (RateLimits, error) {
+	out := make(RateLimits, len(rl))
+	for key, s := range rl {
+		name, model, _ := strings.Cut(key, "/")
+		spec, ok := provider.Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("%q: unknown provider %q", key, name)
+		}
+		if s.RPM < 0 || s.TPM < 0 {
+			return nil, fmt.Errorf("%q: rpm and tpm must not be negative", key)
+		}
+		if model != "" {
+			out[spec.Name+"/"+model] = s
+		} else {
+			out[spec.Name] = s
+		}
+	}
+	return out, nil
+}
+
+// bucket is a token bucket holding up to one minute of capacity, refilled
+// continuously. Reservations may drive it negative; later callers then wait
+// for the debt to be repaid, which keeps the order fair.
+type bucket struct {
+	capacity float64
+	perSec   float64
+	tokens   float64
+	last     time.Time
+}
+
+func newBucket(perMinute int, now time.Time) *bucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	c := float64(perMinute)
+	return &bucket{capacity: c, perSec: c / 60, tokens: c, last: now}
+}
+
+func (b *bucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.perSec
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+}
+
+// delay is how long until n tokens are available.
+func (b *bucket) delay(n float64) time.Duration {
+	if b == nil || b.tokens >= n {
+		return 0
+	}
+	return time.Duration((n - b.tokens) / b.perSec * float64(time.Second))
+}
+
+type rateLimiter struct {
+	mu       sync.Mutex
+	spec     RateLimitSpec
+	requests *bucket
+	tokens   *bucket
+}
+
+var rateLimiters = struct {
+	sync.Mutex
+	m map[string]*rateLimiter
+}{m: map[string]*rateLimiter{}}
+
+// limiterFor returns the shared limiter of a provider/model pair, or nil when
+// it is not limited. Pairs covered by the same provider entry share one.
+func limiterFor(rl RateLimits, providerName, model string) *rateLimiter {
+	key, spec, ok := rl.lookup(providerName, model)
+	if !ok || (spec.RPM <= 0 && spec.TPM <= 0) {
+		return nil
+	}
+	rateLimiters.Lock()
+	defer rateLimiters.Unlock()
+	l, ok := rateLimiters.m[key]
+	if !ok || l.spec != spec {
+		now := time.Now()
+		l = &rateLimiter{spec: spec, requests: newBucket(spec.RPM, now), tokens: newBucket(spec.TPM, now)}
+		rateLimiters.m[key] = l
+	}
+	return l
+}
+
+// wait reserves one request and n estimated tokens, sleeping until they are
+// available. When that would outlast ctx's deadline it fails at once with
+// errRateLimited and reserves nothing; if ctx ends during the sleep the
+// reservation is refunded and the error is errRateLimited (deadline) or
+// provider.KindCanceled. A nil limiter never waits.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+	tok := float64(n)
+	l.mu.Lock()
+	now := time.Now()
+	if l.tokens != nil {
+		// A call larger than a whole minute's budget still goes through once
+		// the bucket is full.
+		if tok > l.tokens.capacity {
+			tok = l.tokens.capacity
+		}
+		l.tokens.refill(now)
+	}
+	if l.requests != nil {
+		l.requests.refill(now)
+	}
+	d := max(l.requests.delay(1), l.tokens.delay(tok))
+	if dl, ok := ctx.Deadline(); ok && now.Add(d).After(dl) {
+		l.mu.Unlock()
+		return fmt.Errorf("%w (%s): a call of ~%d tokens would wait %s, past the deadline", errRateLimited, l.describe(), n, d.Round(time.Millisecond))
+	}
+	if l.requests != nil {
+		l.requests.tokens--
+	}
+	if l.tokens != nil {
+		l.tokens.tokens -= tok
+	}
+	l.mu.Unlock()
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		if l.requests != nil {
+			l.requests.tokens++
+		}
+		if l.tokens != nil {
+			l.tokens.tokens += tok
+		}
+		l.mu.Unlock()
+		// Time spent in our own queue says nothing about the provider.
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return &provider.Error{Kind: provider.KindCanceled, Message: "canceled while waiting for local rate limit capacity", Err: ctx.Err()}
+		}
+		return fmt.Errorf("%w (%s): deadline reached while waiting for capacity", errRateLimited, l.describe())
+	}
+}
+
+func (l *rateLimiter) describe() string {
+	var parts []string
+	if l.spec.RPM > 0 {
+		parts = append(parts, fmt.Sprintf("%d rpm", l.spec.RPM))
+	}
+	if l.spec.TPM > 0 {
+		parts = append(parts, fmt.Sprintf("%d tpm", l.spec.TPM))
+	}
+	return strings.Join(parts, ", ")
+}
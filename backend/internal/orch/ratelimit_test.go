@@ -0,0 +1,137 @@
+package orch
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/you/swarmone/internal/provider"
+)
+
+func newTestLimiter(spec RateLimitSpec) *rateLimiter {
+	now := time.Now()
+	return &rateLimiter{spec: spec, requests: newBucket(spec.RPM, now), tokens: newBucket(spec.TPM, now)}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	empty := 0.0
+	tests := []struct {
+		name     string
+		spec     RateLimitSpec
+		requests *float64 // starting request bucket level; nil = full
+		tokens   *float64 // starting token bucket level; nil = full
+		n        int
+		wantErr  error
+		minDelay time.Duration
+	}{
+		{name: "capacity available", spec: RateLimitSpec{RPM: 60, TPM: 1000}, n: 100},
+		{name: "out of requests past the deadline", spec: RateLimitSpec{RPM: 1}, requests: &empty, wantErr: errRateLimited},
+		{name: "out of tokens past the deadline", spec: RateLimitSpec{TPM: 600}, tokens: &empty, n: 300, wantErr: errRateLimited},
+		{name: "short wait within the deadline", spec: RateLimitSpec{RPM: 6000}, requests: &empty, minDelay: 5 * time.Millisecond},
+		{name: "a call above a minute's budget is capped", spec: RateLimitSpec{TPM: 600}, n: 5000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLimiter(tt.spec)
+			if tt.requests != nil {
+				l.requests.tokens = *tt.requests
+			}
+			if tt.tokens != nil {
+				l.tokens.tokens = *tt.tokens
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			start := time.Now()
+			err := l.wait(ctx, tt.n)
+			el := time.Since(start)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("wait = %v, want %v", err, tt.wantErr)
+			}
+			if el < tt.minDelay || el > tt.minDelay+500*time.Millisecond {
+				t.Errorf("wait took %s, want about %s", el, tt.minDelay)
+			}
+		})
+	}
+}
+
+func TestRateLimiterRefusalReservesNothing(t *testing.T) {
+	l := newTestLimiter(RateLimitSpec{RPM: 1, TPM: 1000})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := l.wait(ctx, 10); err != nil {
+		t.Fatalf("first wait: %v", err)
+	}
+	req, tok := l.requests.tokens, l.tokens.tokens
+	if err := l.wait(ctx, 10); !errors.Is(err, errRateLimited) {
+		t.Fatalf("second wait = %v, want errRateLimited", err)
+	}
+	if l.requests.tokens > req+0.01 || l.requests.tokens < req-0.01 || l.tokens.tokens < tok-1 {
+		t.Errorf("refused wait reserved capacity: requests %.3f→%.3f, tokens %.1f→%.1f", req, l.requests.tokens, tok, l.tokens.tokens)
+	}
+}
+
+// hiddenDeadline hides its deadline from wait's up-front check, so the
+// deadline passes during the sleep.
+type hiddenDeadline struct{ context.Context }
+
+func (hiddenDeadline) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func TestRateLimiterRefundsOnContextEnd(t *testing.T) {
+	tests := []struct {
+		name     string
+		cancel   bool // cancel instead of letting the deadline pass
+		wantErr  error
+		wantKind provider.ErrorKind
+	}{
+		{name: "deadline during the sleep is a local rate limit", wantErr: errRateLimited, wantKind: provider.KindUnknown},
+		{name: "cancellation is canceled", cancel: true, wantErr: provider.ErrCanceled, wantKind: provider.KindCanceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 60 rpm with the bucket two calls in debt: this call needs ~3s.
+			l := newTestLimiter(RateLimitSpec{RPM: 60, TPM: 60000})
+			l.requests.tokens = -2
+			before := l.tokens.tokens
+
+			var ctx context.Context
+			var cancel context.CancelFunc
+			if tt.cancel {
+				ctx, cancel = context.WithCancel(context.Background())
+				time.AfterFunc(50*time.Millisecond, cancel)
+			} else {
+				ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+				ctx = hiddenDeadline{ctx}
+			}
+			defer cancel()
+
+			err := l.wait(ctx, 500)
+			if !errors.Is(err, tt.wantErr) || provider.AsError(err).Kind != tt.wantKind {
+				t.Fatalf("wait = %v, want %v (kind %q)", err, tt.wantErr, tt.wantKind)
+			}
+			if providerFault(err) {
+				t.Error("a local wait counts against the breaker")
+			}
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if l.requests.tokens < -2.01 || l.tokens.tokens < before-1 {
+				t.Errorf("reservation not refunded: requests %.3f, tokens %.1f (was %.1f)", l.requests.tokens, l.tokens.tokens, before)
+			}
+		})
+	}
+}
+
+func TestNilRateLimiter(t *testing.T) {
+	var l *rateLimiter
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.wait(ctx, 1<<20); err != nil {
+		t.Errorf("nil limiter wait = %v", err)
+	}
+	if limiterFor(RateLimits{"openai": {}}, "openai", "m") != nil {
+		t.Error("a zero spec produced a limiter")
+	}
+	if limiterFor(RateLimits{"anthropic": {RPM: 5}}, "claude", "ratelimit-test") != limiterFor(RateLimits{"anthropic": {RPM: 5}}, "anthropic", "other") {
+		t.Error("models under one provider entry got different limiters")
+	}
+}
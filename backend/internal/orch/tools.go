@@ -0,0 +1,150 @@
+package orch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/you/swarmone/internal/provider"
+)
+
+// ToolHandler executes a tool call locally. args is the JSON object produced
+// by the model. The returned string (or the error text) is sent back to the
+// model as the tool result.
+type ToolHandler func(ctx context.Context, args json.RawMessage) (string, error)
+
+type registeredTool struct {
+	def     provider.Tool
+	handler ToolHandler
+}
+
+var toolRegistry = struct {
+	sync.RWMutex
+	m map[string]registeredTool
+}{m: map[string]registeredTool{}}
+
+// errToolLoop is returned when a runner still asks for tools after MaxToolRounds calls.
+var errToolLoop = errors.New("tool call limit reached without a final answer")
+
+const defaultMaxToolRounds = 4
+
+// RegisterTool makes a tool available to runners that list it in RunnerSpec.Tools.
+// Registering the same name twice is an error.
+func RegisterTool(t provider.Tool, h ToolHandler) error {
+	name := strings.TrimSpace(t.Name)
+	if name == "" || h == nil {
+		return errors.New("tool name and handler are required")
+	}
+	if len(t.Parameters) > 0 && !json.Valid(t.Parameters) {
+		return fmt.Errorf("tool %q: parameters are not valid JSON", name)
+	}
+	t.Name = name
+	toolRegistry.Lock()
+	defer toolRegistry.Unlock()
+	if _, dup := toolRegistry.m[name]; dup {
+		return fmt.Errorf("tool %q already registered", name)
+	}
+	toolRegistry.m[name] = registeredTool{def: t, handler: h}
+	return nil
+}
+
+// toolsFor resolves a runner's tool names to declarations ("*" selects every
+// registered tool, sorted by name so requests are stable).
+func toolsFor(names []string) ([]provider.Tool, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	toolRegistry.RLock()
+	defer toolRegistry.RUnlock()
+	var out []provider.Tool
+	seen := map[string]bool{}
+	for _, n := range names {
+		if n == "*" {
+			all := make([]string, 0, len(toolRegistry.m))
+			for k := range toolRegistry.m {
+				all = append(all, k)
+			}
+			sort.Strings(all)
+			for _, k := range all {
+				if !seen[k] {
+					seen[k] = true
+					out = append(out, toolRegistry.m[k].def)
+				}
+			}
+			continue
+		}
+		rt, ok := toolRegistry.m[n]
+		if !ok {
+			return nil, fmt.Errorf("unknown tool %q", n)
+		}
+		if !seen[n] {
+			seen[n] = true
+			out = append(out, rt.def)
+		}
+	}
+	return out, nil
+}
+
+// runTool executes one call. Unknown tools and handler errors are reported
+// to the model as the tool output so it can recover; isErr flags them.
+func runTool(ctx context.Context, call provider.ToolCall) (output string, isErr bool) {
+	toolRegistry.RLock()
+	rt, ok := toolRegistry.m[call.Name]
+	toolRegistry.RUnlock()
+	if !ok {
+		return fmt.Sprintf("error: unknown tool %q", call.Name), true
+	}
+	out, err := rt.handler(ctx, call.Arguments)
+	if err != nil {
+		return "error: " + err.Error(), true
+	}
+	return out, false
+}
+
+// converse sends req via call and, while the model asks for tools, runs them
+// and sends the results back, up to maxRounds model calls. Usage and attempts
+// are summed over all rounds; FinishReason and RequestID come from the last one.
+// onTool (may be nil) is told about every executed call.
+func converse(ctx context.Context, req provider.Request, maxRounds int, call func(provider.Request) (provider.Result, error), onTool func(provider.ToolCall, string, bool)) (provider.Result, int, error) {
+	if maxRounds <= 0 {
+		maxRounds = defaultMaxToolRounds
+	}
+	var total provider.Result
+	ncalls := 0
+	for round := 0; ; round++ {
+		out, err := call(req)
+		total.Usage.Add(out.Usage)
+		total.Attempts += out.Attempts
+		total.FinishReason = out.FinishReason
+		total.RequestID = out.RequestID
+		total.Text = out.Text
+		total.ToolCalls = out.ToolCalls
+		if err != nil || len(out.ToolCalls) == 0 {
+			return total, ncalls, err
+		}
+		if len(req.Tools) == 0 {
+			// Calls to tools that were never offered; only the text counts.
+			if strings.TrimSpace(out.Text) == "" {
+				err = &provider.Error{Kind: provider.KindEmptyOutput, Message: "model requested tools but none are configured"}
+			}
+			return total, ncalls, err
+		}
+		if round+1 >= maxRounds {
+			return total, ncalls, errToolLoop
+		}
+
+		req.Messages = append(req.Messages, provider.Message{Role: provider.RoleAssistant, Content: out.Text, ToolCalls: out.ToolCalls, Thinking: out.Thinking})
+		for _, tc := range out.ToolCalls {
+			output, isErr := runTool(ctx, tc)
+			ncalls++
+			if onTool != nil {
+				onTool(tc, output, isErr)
+			}
+			req.Messages = append(req.Messages, provider.ToolResult(tc, output))
+		}
+	}
+}
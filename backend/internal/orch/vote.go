@@ -0,0 +1,99 @@
+package orch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Consensus modes.
+const (
+	ModeJudge = "judge" // a judge model scores the candidates (default)
+	ModeExact = "exact" // plurality vote over (normalized) identical answers; never calls the judge
+)
+
+// errNoConsensus is returned in exact mode when no two answers agree.
+var errNoConsensus = errors.New("no consensus")
+
+// Normalizations applied to answers before they are compared in exact mode.
+const (
+	NormWhitespace  = "whitespace"  // collapse runs of whitespace to one space
+	NormCase        = "case"        // lower-case
+	NormPunctuation = "punctuation" // drop punctuation
+	NormJSON        = "json"        // compare JSON answers canonically (key order, spacing)
+)
+
+func validNormalize(names []string) error {
+	for _, n := range names {
+		switch n {
+		case NormWhitespace, NormCase, NormPunctuation, NormJSON:
+		default:
+			return fmt.Errorf("unknown normalization %q (want whitespace, case, punctuation or json)", n)
+		}
+	}
+	return nil
+}
+
+// normalize returns the comparison key of an answer. JSON canonicalization
+// comes first, so the text options then only touch non-JSON answers.
+func normalize(s string, opts []string) string {
+	s = strings.TrimSpace(s)
+	has := func(o string) bool {
+		for _, x := range opts {
+			if x == o {
+				return true
+			}
+		}
+		return false
+	}
+	if has(NormJSON) {
+		var v any
+		if err := json.Unmarshal([]byte(stripCodeFence(s)), &v); err == nil {
+			b, _ := json.Marshal(v) // map keys come out sorted
+			return string(b)
+		}
+	}
+	if has(NormCase) {
+		s = strings.ToLower(s)
+	}
+	if has(NormPunctuation) {
+		s = strings.Map(func(r rune) rune {
+			if unicode.IsPunct(r) {
+				return -1
+			}
+			return r
+		}, s)
+	}
+	if has(NormWhitespace) {
+		s = strings.Join(strings.Fields(s), " ")
+	}
+	return s
+}
+
+// plurality groups the candidates by normalized answer. It returns the votes
+// of each runner's answer (index-aligned with runners, 0 for runners without
+// a candidate) and the winning runner: the first runner of the largest group,
+// so ties go to the lowest index. When more than one answer came back and no
+// two agree, winner is -1.
+func plurality(cands []cand, runners int, opts []string) (votes []int, winner int) {
+	votes = make([]int, runners)
+	keys := make([]string, len(cands))
+	count := map[string]int{}
+	for i, c := range cands {
+		keys[i] = normalize(c.Text, opts)
+		count[keys[i]]++
+	}
+	winner, best := -1, 0
+	for i, c := range cands {
+		votes[c.Orig] = count[keys[i]]
+		if count[keys[i]] > best {
+			winner, best = c.Orig, count[keys[i]]
+		}
+	}
+	if best == 1 && len(cands) > 1 {
+		return votes, -1
+	}
+	return votes, winner
+}
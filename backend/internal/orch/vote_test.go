@@ -0,0 +1,115 @@
+package orch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		opts []string
+		want string
+	}{
+		{"  Paris.  ", nil, "Paris."},
+		{"The  answer\n is\t42", []string{NormWhitespace}, "The answer is 42"},
+		{"PARIS", []string{NormCase}, "paris"},
+		{"Paris, France!", []string{NormPunctuation}, "Paris France"},
+		{"  Hello,   World! ", []string{NormWhitespace, NormCase, NormPunctuation}, "hello world"},
+		{`{"b": 1, "a": [1, 2]}`, []string{NormJSON}, `{"a":[1,2],"b":1}`},
+		{"```json\n{\"a\": 1}\n```", []string{NormJSON}, `{"a":1}`},
+		// JSON canonicalization comes first: case is not applied to JSON answers.
+		{`{"Name": "X"}`, []string{NormJSON, NormCase}, `{"Name":"X"}`},
+		// Non-JSON answers still get the text steps.
+		{"Not JSON", []string{NormJSON, NormCase}, "not json"},
+	}
+	for _, tt := range tests {
+		if got := normalize(tt.in, tt.opts); got != tt.want {
+			t.Errorf("normalize(%q, %v) = %q, want %q", tt.in, tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestPlurality(t *testing.T) {
+	tests := []struct {
+		name       string
+		cands      []cand
+		runners    int
+		opts       []string
+		wantVotes  []int
+		wantWinner int
+	}{
+		{
+			name:       "unanimous",
+			cands:      []cand{{0, "4"}, {1, "4"}, {2, "4"}},
+			runners:    3,
+			wantVotes:  []int{3, 3, 3},
+			wantWinner: 0,
+		},
+		{
+			name:       "two of three",
+			cands:      []cand{{0, "5"}, {1, "4"}, {2, "4"}},
+			runners:    3,
+			wantVotes:  []int{1, 2, 2},
+			wantWinner: 1,
+		},
+		{
+			name:       "agreement only after normalization",
+			cands:      []cand{{0, "Paris."}, {1, "paris"}, {2, "Lyon"}},
+			runners:    3,
+			opts:       []string{NormCase, NormPunctuation},
+			wantVotes:  []int{2, 2, 1},
+			wantWinner: 0,
+		},
+		{
+			name:       "no agreement has no winner",
+			cands:      []cand{{0, "a"}, {1, "b"}, {2, "c"}},
+			runners:    3,
+			wantVotes:  []int{1, 1, 1},
+			wantWinner: -1,
+		},
+		{
+			name:       "a tie goes to the lowest index",
+			cands:      []cand{{0, "b"}, {1, "a"}, {2, "a"}, {3, "b"}},
+			runners:    4,
+			wantVotes:  []int{2, 2, 2, 2},
+			wantWinner: 0,
+		},
+		{
+			name:       "plurality without a majority",
+			cands:      []cand{{0, "a"}, {1, "b"}, {2, "b"}, {3, "c"}, {4, "d"}},
+			runners:    5,
+			wantVotes:  []int{1, 2, 2, 1, 1},
+			wantWinner: 1,
+		},
+		{
+			name:       "a one-one split has no winner",
+			cands:      []cand{{0, "a"}, {2, "b"}},
+			runners:    3,
+			wantVotes:  []int{1, 0, 1},
+			wantWinner: -1,
+		},
+		{
+			name:       "votes count the answers received, failed runners get 0",
+			cands:      []cand{{1, "x"}, {3, "x"}, {4, "y"}},
+			runners:    5,
+			wantVotes:  []int{0, 2, 0, 2, 1},
+			wantWinner: 1,
+		},
+		{
+			name:       "a lone answer wins",
+			cands:      []cand{{2, "only"}},
+			runners:    3,
+			wantVotes:  []int{0, 0, 1},
+			wantWinner: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			votes, winner := plurality(tt.cands, tt.runners, tt.opts)
+			if !reflect.DeepEqual(votes, tt.wantVotes) || winner != tt.wantWinner {
+				t.Errorf("plurality = %v, %d; want %v, %d", votes, winner, tt.wantVotes, tt.wantWinner)
+			}
+		})
+	}
+}
@@ -4,8 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"io"
 	"net/http"
 	"os"
@@ -20,6 +18,7 @@ type Anthropic struct {
 	Model string
 	Key   string
 	HTTP  *http.Client
+	Retry RetryPolicy
 }
 
 func NewAnthropic(model, key string) Client {
@@ -36,57 +35,62 @@ func (a *Anthropic) ensureHTTP() {
 			timeout = d
 		}
 	}
-	a.HTTP = &http.Client{Timeout: timeout}
+	a.HTTP = newHTTPClient("anthropic", timeout)
 }
 
-func (a *Anthropic) Generate(ctx context.Context, prompt string, maxTokens int) (string, string, error) {
-	if a.Key == "" {
-		return "", "", errors.New("anthropic api key missing")
+func (a *Anthropic) Generate(ctx context.Context, r Request) (Result, error) {
+	if a.Key == "" && !replaying() {
+		return Result{}, newError("anthropic", KindAuth, "api key missing")
 	}
 	a.ensureHTTP()
-	if maxTokens <= 0 {
-		maxTokens = 256
-	}
-
-	payload := map[string]any{
-		"model":       a.Model,
-		"max_tokens":  maxTokens,
-		"messages":    []map[string]any{{"role": "user", "content": prompt}},
-		"temperature": 0,
-	}
-	b, _ := json.Marshal(payload)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.anthropic.com/v1/messages", bytes.NewReader(b))
-	if err != nil {
-		return "", "", err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", a.Key)
-	req.Header.Set("anthropic-version", "2023-06-01")
 
-	resp, err := a.HTTP.Do(req)
+	resp, attempts, err := a.Retry.do(ctx, a.HTTP, func() (*http.Request, error) {
+		return a.newRequest(ctx, a.payload(r))
+	})
 	if err != nil {
-		return "", "", err
+		return Result{Attempts: attempts}, transportError(ctx, "anthropic", err)
 	}
 	defer resp.Body.Close()
 
 	raw, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", "", fmt.Errorf("anthropic http %d: %s", resp.StatusCode, string(raw))
+		return Result{Attempts: attempts}, httpError("anthropic", resp.StatusCode, raw)
 	}
 
-	// content is an array of blocks; we concatenate text blocks
+	// content is an array of blocks; we concatenate text blocks and collect tool_use blocks
 	var jr struct {
+		ID      string `json:"id"`
 		Content []struct {
-			Type string `json:"type"`
-			Text string `json:"text"`
+			Type  string          `json:"type"`
+			Text  string          `json:"text"`
+			ID    string          `json:"id"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
 		} `json:"content"`
+		StopReason string         `json:"stop_reason"`
+		Usage      anthropicUsage `json:"usage"`
 	}
 	if err := json.Unmarshal(raw, &jr); err != nil {
-		return "", "", fmt.Errorf("anthropic decode error: %v; body=%s", err, string(raw))
+		return Result{Attempts: attempts}, newError("anthropic", KindBadResponse, "decode error: %v; body=%s", err, string(raw))
+	}
+	res := Result{
+		Attempts:     attempts,
+		FinishReason: jr.StopReason,
+		RequestID:    firstNonEmpty(resp.Header.Get("request-id"), jr.ID),
+		Usage:        jr.Usage.toUsage(),
 	}
+	var blocks struct {
+		Content []json.RawMessage `json:"content"`
+	}
+	_ = json.Unmarshal(raw, &blocks)
 	var sb strings.Builder
-	for _, p := range jr.Content {
+	for i, p := range jr.Content {
+		if p.Type == "thinking" || p.Type == "redacted_thinking" {
+			res.Thinking = append(res.Thinking, blocks.Content[i])
+		}
+		if strings.ToLower(p.Type) == "tool_use" {
+			res.ToolCalls = append(res.ToolCalls, ToolCall{ID: p.ID, Name: p.Name, Arguments: p.Input})
+		}
 		if strings.ToLower(p.Type) == "text" && strings.TrimSpace(p.Text) != "" {
 			if sb.Len() > 0 {
 				sb.WriteByte('\n')
@@ -94,9 +98,316 @@ func (a *Anthropic) Generate(ctx context.Context, prompt string, maxTokens int)
 			sb.WriteString(strings.TrimSpace(p.Text))
 		}
 	}
-	txt := strings.TrimSpace(sb.String())
-	if txt == "" {
-		return "", "", errors.New("anthropic empty output")
+	res.Text = strings.TrimSpace(sb.String())
+	takeOutputCall(r, &res)
+	if res.Text == "" && len(res.ToolCalls) == 0 {
+		return res, newError("anthropic", KindEmptyOutput, "empty output")
+	}
+	return res, nil
+}
+
+// Stream sends the same request with "stream": true and forwards
+// content_block_delta text deltas to onDelta. Input usage arrives in
+// message_start, output usage and stop reason in message_delta. Tool calls
+// start with a tool_use content block whose input arrives as input_json_delta
+// fragments; those of the structured-output tool are forwarded to onDelta.
+func (a *Anthropic) Stream(ctx context.Context, r Request, onDelta func(string)) (Result, error) {
+	if a.Key == "" && !replaying() {
+		return Result{}, newError("anthropic", KindAuth, "api key missing")
+	}
+	a.ensureHTTP()
+
+	payload := a.payload(r)
+	payload["stream"] = true
+	resp, attempts, err := a.Retry.do(ctx, a.HTTP, func() (*http.Request, error) {
+		req, err := a.newRequest(ctx, payload)
+		if err == nil {
+			req.Header.Set("Accept", "text/event-stream")
+		}
+		return req, err
+	})
+	if err != nil {
+		return Result{Attempts: attempts}, transportError(ctx, "anthropic", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		raw, _ := io.ReadAll(resp.Body)
+		return Result{Attempts: attempts}, httpError("anthropic", resp.StatusCode, raw)
+	}
+
+	res := Result{Attempts: attempts, RequestID: resp.Header.Get("request-id")}
+	var usage anthropicUsage
+	var sb strings.Builder
+	type toolBlock struct {
+		call   ToolCall
+		args   strings.Builder
+		output bool
+	}
+	var tools []*toolBlock
+	byIndex := map[int]*toolBlock{}
+	var thinking []map[string]any // thinking blocks rebuilt from their deltas
+	thinkingAt := map[int]map[string]any{}
+	err = readSSE(resp.Body, func(event, data string) error {
+		var ev struct {
+			Type    string `json:"type"`
+			Index   int    `json:"index"`
+			Message struct {
+				ID    string         `json:"id"`
+				Usage anthropicUsage `json:"usage"`
+			} `json:"message"`
+			ContentBlock struct {
+				Type string `json:"type"`
+				ID   string `json:"id"`
+				Name string `json:"name"`
+				Data string `json:"data"` // redacted_thinking
+			} `json:"content_block"`
+			Delta struct {
+				Type        string `json:"type"`
+				Text        string `json:"text"`
+				PartialJSON string `json:"partial_json"`
+				Thinking    string `json:"thinking"`
+				Signature   string `json:"signature"`
+				StopReason  string `json:"stop_reason"`
+			} `json:"delta"`
+			Usage *anthropicUsage `json:"usage"`
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return newError("anthropic", KindBadResponse, "stream decode error: %v; data=%s", err, data)
+		}
+		switch ev.Type {
+		case "message_start":
+			usage = ev.Message.Usage
+			if res.RequestID == "" {
+				res.RequestID = ev.Message.ID
+			}
+		case "content_block_start":
+			switch ev.ContentBlock.Type {
+			case "thinking":
+				b := map[string]any{"type": "thinking", "thinking": "", "signature": ""}
+				thinking = append(thinking, b)
+				thinkingAt[ev.Index] = b
+			case "redacted_thinking":
+				thinking = append(thinking, map[string]any{"type": "redacted_thinking", "data": ev.ContentBlock.Data})
+			}
+			if ev.ContentBlock.Type == "tool_use" {
+				tb := &toolBlock{call: ToolCall{ID: ev.ContentBlock.ID, Name: ev.ContentBlock.Name}}
+				tb.output = r.Output != nil && tb.call.Name == r.Output.name()
+				tools = append(tools, tb)
+				byIndex[ev.Index] = tb
+			}
+		case "content_block_delta":
+			if ev.Delta.Type == "text_delta" && ev.Delta.Text != "" {
+				sb.WriteString(ev.Delta.Text)
+				if onDelta != nil {
+					onDelta(ev.Delta.Text)
+				}
+			}
+			if b := thinkingAt[ev.Index]; b != nil {
+				switch ev.Delta.Type {
+				case "thinking_delta":
+					b["thinking"] = b["thinking"].(string) + ev.Delta.Thinking
+				case "signature_delta":
+					b["signature"] = b["signature"].(string) + ev.Delta.Signature
+				}
+			}
+			if ev.Delta.Type == "input_json_delta" {
+				if tb := byIndex[ev.Index]; tb != nil {
+					tb.args.WriteString(ev.Delta.PartialJSON)
+					if tb.output && onDelta != nil && ev.Delta.PartialJSON != "" {
+						onDelta(ev.Delta.PartialJSON)
+					}
+				}
+			}
+		case "message_delta":
+			if ev.Delta.StopReason != "" {
+				res.FinishReason = ev.Delta.StopReason
+			}
+			if ev.Usage != nil && ev.Usage.OutputTokens > 0 {
+				usage.OutputTokens = ev.Usage.OutputTokens
+			}
+		case "error":
+			return streamError("anthropic", ev.Error.Type, ev.Error.Message)
+		}
+		return nil
+	})
+	res.Usage = usage.toUsage()
+	for _, b := range thinking {
+		raw, _ := json.Marshal(b)
+		res.Thinking = append(res.Thinking, raw)
+	}
+	for _, tb := range tools {
+		tb.call.Arguments = argsFromString(firstNonEmpty(tb.args.String(), "{}"))
+		res.ToolCalls = append(res.ToolCalls, tb.call)
+	}
+	if err != nil {
+		return res, transportError(ctx, "anthropic", err)
+	}
+
+	res.Text = strings.TrimSpace(sb.String())
+	takeOutputCall(r, &res)
+	if res.Text == "" && len(res.ToolCalls) == 0 {
+		return res, newError("anthropic", KindEmptyOutput, "empty output")
+	}
+	return res, nil
+}
+
+// anthropicUsage is the Messages API usage block. input_tokens excludes
+// cache reads and writes, so toUsage adds them back.
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+}
+
+func (u anthropicUsage) toUsage() Usage {
+	return Usage{
+		InputTokens:      u.InputTokens + u.CacheReadInputTokens + u.CacheCreationInputTokens,
+		OutputTokens:     u.OutputTokens,
+		CachedTokens:     u.CacheReadInputTokens,
+		CacheWriteTokens: u.CacheCreationInputTokens,
+	}
+}
+
+// payload maps system messages to the top-level "system" field and the
+// remaining user/assistant turns to "messages". Assistant tool calls become
+// tool_use blocks; consecutive tool results are merged into one user message
+// of tool_result blocks, as the API requires. Cache marks become
+// cache_control breakpoints.
+func (a *Anthropic) payload(r Request) map[string]any {
+	maxTokens := r.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = 256
+	}
+	marks := r.cacheMarks()
+	msgs := make([]map[string]any, 0, len(r.Messages))
+	var results []map[string]any // tool_result blocks of the message being built
+	for i, m := range r.Messages {
+		if m.Role == RoleSystem {
+			continue
+		}
+		switch {
+		case m.Role == RoleTool:
+			block := map[string]any{"type": "tool_result", "tool_use_id": m.ToolCallID, "content": m.Content}
+			if marks[i] {
+				block["cache_control"] = ephemeral
+			}
+			if results != nil {
+				results = append(results, block)
+				msgs[len(msgs)-1]["content"] = results
+				continue
+			}
+			results = []map[string]any{block}
+			msgs = append(msgs, map[string]any{"role": RoleUser, "content": results})
+			continue
+		case len(m.ToolCalls) > 0:
+			// Thinking blocks must come back unchanged and first.
+			var blocks []any
+			for _, t := range m.Thinking {
+				blocks = append(blocks, t)
+			}
+			if m.Content != "" {
+				blocks = append(blocks, map[string]any{"type": "text", "text": m.Content})
+			}
+			for _, tc := range m.ToolCalls {
+				blocks = append(blocks, map[string]any{"type": "tool_use", "id": tc.ID, "name": tc.Name, "input": tc.args()})
+			}
+			msgs = append(msgs, map[string]any{"role": RoleAssistant, "content": blocks})
+		case len(m.Attachments) > 0:
+			// Files first, as Anthropic recommends, then the text.
+			var blocks []any
+			for _, a := range m.Attachments {
+				typ := "image"
+				if a.Modality() == ModalityDocument {
+					typ = "document"
+				}
+				blocks = append(blocks, map[string]any{"type": typ, "source": map[string]any{
+					"type":       "base64",
+					"media_type": a.MIMEType,
+					"data":       a.base64(),
+				}})
+			}
+			blocks = append(blocks, map[string]any{"type": "text", "text": m.Content})
+			msgs = append(msgs, map[string]any{"role": m.Role, "content": blocks})
+		default:
+			msgs = append(msgs, map[string]any{"role": m.Role, "content": m.Content})
+		}
+		if marks[i] {
+			markCache(msgs[len(msgs)-1])
+		}
+		results = nil
+	}
+	thinking := r.Reasoning.budget()
+	payload := map[string]any{
+		"model":      a.Model,
+		"max_tokens": r.Reasoning.outputBudget(maxTokens),
+		"messages":   msgs,
+	}
+	if thinking > 0 {
+		payload["thinking"] = map[string]any{"type": "enabled", "budget_tokens": thinking}
+	}
+	if s := r.Sampling; s.Temperature != nil {
+		payload["temperature"] = *s.Temperature
+	}
+	if s := r.Sampling; s.TopP != nil {
+		payload["top_p"] = *s.TopP
+	}
+	if s := r.Sampling; s.TopK != nil {
+		payload["top_k"] = *s.TopK
+	}
+	if s := r.Sampling; len(s.Stop) > 0 {
+		payload["stop_sequences"] = s.Stop
 	}
-	return txt, "", nil
+	if sys := r.System(); sys != "" {
+		payload["system"] = sys
+		for i, m := range r.Messages {
+			if m.Role == RoleSystem && marks[i] {
+				payload["system"] = []any{map[string]any{"type": "text", "text": sys, "cache_control": ephemeral}}
+				break
+			}
+		}
+	}
+	defs := r.Tools
+	if r.Output != nil {
+		// Structured output is a tool the model must call; with other tools
+		// present it may call those first ("any"), and the output tool ends the turn.
+		defs = append(append([]Tool(nil), r.Tools...), r.Output.outputTool())
+		// Thinking only allows tool_choice "auto", so the output tool is
+		// then merely offered.
+		switch {
+		case thinking > 0:
+			payload["tool_choice"] = map[string]any{"type": "auto"}
+		case len(r.Tools) == 0:
+			payload["tool_choice"] = map[string]any{"type": "tool", "name": r.Output.name()}
+		default:
+			payload["tool_choice"] = map[string]any{"type": "any"}
+		}
+	}
+	if len(defs) > 0 {
+		tools := make([]map[string]any, 0, len(defs))
+		for _, t := range defs {
+			tools = append(tools, map[string]any{"name": t.Name, "description": t.Description, "input_schema": t.schema()})
+		}
+		payload["tools"] = tools
+	}
+	return payload
 }
+
+func (a *Anthropic) newRequest(ctx context.Context, payload map[string]any) (*http.Request, error) {
+	b, _ := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.anthropic.com/v1/messages", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", a.Key)
+	req.Header.Set("anthropic-version", "2023-06-01")
+	return req, nil
+}
+
+func (a *Anthropic) SetRetryPolicy(p RetryPolicy) { a.Retry = p }
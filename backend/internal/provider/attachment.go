@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Attachment is a file sent along with a user message. Data is raw bytes
+// (base64 in JSON).
+type Attachment struct {
+	Name     string `json:"name,omitempty"`
+	MIMEType string `json:"mime_type"`
+	Data     []byte `json:"data"`
+}
+
+// Modalities of attachments.
+const (
+	ModalityImage    = "image"
+	ModalityDocument = "document"
+)
+
+var attachmentTypes = map[string]string{
+	"image/png":       ModalityImage,
+	"image/jpeg":      ModalityImage,
+	"image/gif":       ModalityImage,
+	"image/webp":      ModalityImage,
+	"application/pdf": ModalityDocument,
+}
+
+// NewAttachment builds an attachment, sniffing the MIME type when mimeType is
+// empty, and rejects types no provider accepts.
+func NewAttachment(name, mimeType string, data []byte) (Attachment, error) {
+	if len(data) == 0 {
+		return Attachment{}, fmt.Errorf("attachment %q is empty", name)
+	}
+	mt := strings.ToLower(strings.TrimSpace(mimeType))
+	if i := strings.IndexByte(mt, ';'); i >= 0 {
+		mt = strings.TrimSpace(mt[:i])
+	}
+	if mt == "" || mt == "application/octet-stream" {
+		mt, _, _ = strings.Cut(http.DetectContentType(data), ";")
+	}
+	if _, ok := attachmentTypes[mt]; !ok {
+		return Attachment{}, fmt.Errorf("attachment %q: unsupported type %q (want PNG, JPEG, GIF, WebP or PDF)", name, mt)
+	}
+	return Attachment{Name: name, MIMEType: mt, Data: data}, nil
+}
+
+// Modality is ModalityImage or ModalityDocument.
+func (a Attachment) Modality() string { return attachmentTypes[a.MIMEType] }
+
+func (a Attachment) base64() string { return base64.StdEncoding.EncodeToString(a.Data) }
+
+func (a Attachment) dataURL() string { return "data:" + a.MIMEType + ";base64," + a.base64() }
+
+// Modalities lists the attachment modalities a provider accepts (from its
+// Capabilities). For Chat Completions servers and Ollama, whether a given
+// model can actually see images is up to the server.
+func Modalities(provider string) []string {
+	caps, _ := capabilities(provider)
+	return caps.Modalities
+}
+
+// CheckAttachments reports the first attachment in r that provider cannot take.
+func CheckAttachments(provider string, r Request) error {
+	ok := map[string]bool{}
+	for _, m := range Modalities(provider) {
+		ok[m] = true
+	}
+	for _, m := range r.Messages {
+		for _, a := range m.Attachments {
+			if !ok[a.Modality()] {
+				return &Error{
+					Provider: provider,
+					Kind:     KindUnsupportedInput,
+					Message:  fmt.Sprintf("%s attachments (%s) are not supported", a.Modality(), a.MIMEType),
+				}
+			}
+		}
+	}
+	return nil
+}
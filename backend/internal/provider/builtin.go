@@ -0,0 +1,152 @@
+package provider
+
+import "os"
+
+var allSampling = []string{"temperature", "top_p", "top_k", "stop", "seed", "presence_penalty", "frequency_penalty"}
+
+var (
+	fieldModel   = ConfigField{Name: "model", Required: true, Description: "model name"}
+	fieldAPIKey  = ConfigField{Name: "api_key", Description: "overrides the provider key (api_key_env reads it from another env var)"}
+	fieldBaseURL = ConfigField{Name: "base_url", Description: "API base URL"}
+)
+
+// The built-in providers. The OpenAI Responses API only takes temperature and
+// top_p; Chat Completions servers and Ollama only see images.
+func init() {
+	MustRegister(Spec{
+		Name:        "openai",
+		Description: "OpenAI Responses API",
+		KeyEnv:      "OPENAI_API_KEY",
+		Fields:      []ConfigField{fieldModel, fieldAPIKey},
+		Capabilities: Capabilities{
+			Streaming: true, Tools: true, StructuredOutput: true, Reasoning: true, Embeddings: true,
+			Modalities: []string{ModalityImage, ModalityDocument},
+			Sampling:   []string{"temperature", "top_p"},
+		},
+		New: func(c Config) (Client, error) { return NewOpenAI(c.Model, c.APIKey), nil },
+	})
+	MustRegister(Spec{
+		Name:        "gemini",
+		Aliases:     []string{"google", "googleai"},
+		Description: "Google Gemini generateContent API",
+		KeyEnv:      "GOOGLE_API_KEY",
+		Fields:      []ConfigField{fieldModel, fieldAPIKey},
+		Capabilities: Capabilities{
+			Streaming: true, Tools: true, StructuredOutput: true, Reasoning: true, Embeddings: true,
+			Modalities:      []string{ModalityImage, ModalityDocument},
+			Sampling:        allSampling,
+			MaxStop:         5,
+			DynamicThinking: true,
+		},
+		New: func(c Config) (Client, error) { return NewGemini(c.Model, c.APIKey), nil },
+	})
+	MustRegister(Spec{
+		Name:        "anthropic",
+		Aliases:     []string{"claude"},
+		Description: "Anthropic Messages API",
+		KeyEnv:      "ANTHROPIC_API_KEY",
+		Fields:      []ConfigField{fieldModel, fieldAPIKey},
+		Capabilities: Capabilities{
+			Streaming: true, Tools: true, StructuredOutput: true, Reasoning: true,
+			Modalities:        []string{ModalityImage, ModalityDocument},
+			Sampling:          []string{"temperature", "top_p", "top_k", "stop"},
+			MaxTemperature:    1,
+			ThinkingMinBudget: 1024,
+		},
+		New: func(c Config) (Client, error) { return NewAnthropic(c.Model, c.APIKey), nil },
+	})
+	MustRegister(Spec{
+		Name:        "openai-compatible",
+		Aliases:     []string{"openai_compatible", "vllm", "llamacpp", "llama.cpp"},
+		Description: "any OpenAI Chat Completions server (vLLM, llama.cpp, LiteLLM, ...)",
+		Fields: []ConfigField{
+			fieldModel,
+			{Name: "base_url", Required: true, Description: `e.g. "http://localhost:8000/v1"`},
+			{Name: "api_key", Description: "optional bearer token"},
+			{Name: "headers", Description: "extra request headers"},
+		},
+		Capabilities: Capabilities{
+			Streaming: true, Tools: true, StructuredOutput: true, Reasoning: true, Embeddings: true,
+			Modalities: []string{ModalityImage},
+			Sampling:   allSampling,
+		},
+		New: func(c Config) (Client, error) {
+			return NewOpenAICompat(c.Model, c.BaseURL, c.APIKey, c.Headers), nil
+		},
+	})
+	MustRegister(Spec{
+		Name:        "ollama",
+		Description: "Ollama native chat API",
+		Fields: []ConfigField{
+			fieldModel,
+			{Name: "base_url", Description: "default $OLLAMA_HOST or http://localhost:11434"},
+			{Name: "options", Description: "Ollama options (num_ctx, ...)"},
+			{Name: "keep_alive", Description: `e.g. "10m", "-1"`},
+		},
+		Capabilities: Capabilities{
+			Streaming: true, Tools: true, StructuredOutput: true, Reasoning: true, Embeddings: true,
+			Modalities: []string{ModalityImage},
+			Sampling:   allSampling,
+		},
+		New: func(c Config) (Client, error) {
+			return NewOllama(c.Model, c.BaseURL, c.Options, c.KeepAlive), nil
+		},
+	})
+	MustRegister(Spec{
+		Name:        "exec",
+		Aliases:     []string{"subprocess", "command"},
+		Description: "local command: request JSON on stdin, answer JSON on stdout",
+		Fields: []ConfigField{
+			{Name: "command", Required: true, Description: `argv, e.g. ["python3", "rules.py"]`},
+			{Name: "model", Description: "passed through in the input"},
+			{Name: "env", Description: "extra environment variables"},
+			{Name: "dir", Description: "working directory"},
+			{Name: "timeout", Description: "per call, default 60s"},
+		},
+		Capabilities: Capabilities{
+			Tools: true, StructuredOutput: true, Reasoning: true,
+			Modalities: []string{ModalityImage, ModalityDocument},
+			Sampling:   allSampling,
+		},
+		New: func(c Config) (Client, error) {
+			return NewExec(c.Model, c.Command, c.Env, c.Dir, c.Timeout), nil
+		},
+	})
+	MustRegister(Spec{
+		Name:        "http",
+		Aliases:     []string{"template", "http-template"},
+		Description: "any JSON endpoint, described by a body template and response paths",
+		Fields: []ConfigField{
+			{Name: "template", Required: true, Description: "url, method, body, response_path, ... (see HTTPTemplate)"},
+			{Name: "model", Description: "{{model}} placeholder"},
+			{Name: "api_key", Description: "{{api_key}} placeholder for url and headers"},
+			{Name: "headers", Description: "request headers; values may use {{api_key}}"},
+			{Name: "timeout", Description: "per call, default 60s"},
+		},
+		New: func(c Config) (Client, error) {
+			return NewTemplateHTTP(c.Model, c.APIKey, c.Headers, *c.Template, c.Timeout)
+		},
+	})
+	MustRegister(Spec{
+		Name:        "mock",
+		Description: "scripted answers from a JSON fixture; no network",
+		Fields: []ConfigField{
+			{Name: "model", Description: "matched by fixture rules"},
+			{Name: "fixture", Description: "fixture path, default $MOCK_FIXTURE"},
+		},
+		Capabilities: Capabilities{
+			Streaming: true, Tools: true, StructuredOutput: true, Reasoning: true, Embeddings: true,
+			Modalities: []string{ModalityImage, ModalityDocument},
+			Sampling:   allSampling,
+		},
+		New: func(c Config) (Client, error) {
+			return NewMock(c.Model, firstNonEmpty(c.Fixture, os.Getenv("MOCK_FIXTURE"))), nil
+		},
+	})
+
+	for _, m := range builtinModels {
+		if err := RegisterModel(m); err != nil {
+			panic(err)
+		}
+	}
+}
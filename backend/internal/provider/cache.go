@@ -0,0 +1,57 @@
+package provider
+
+// Prompt caching.
+//
+// Message.Cache marks a cache breakpoint: the request prefix up to and
+// including that message is expected to repeat across calls. Anthropic gets
+// cache_control breakpoints (at most 4, the last ones win); OpenAI caches
+// prefixes automatically and gets Request.CacheKey as prompt_cache_key to
+// route repeats to the same cache. Gemini and most self-hosted servers cache
+// implicitly. Cache hits are reported as Usage.CachedTokens, cache writes as
+// Usage.CacheWriteTokens.
+
+const maxCacheBreakpoints = 4
+
+var ephemeral = map[string]any{"type": "ephemeral"}
+
+// cacheMarks returns the indexes in r.Messages that keep their breakpoint.
+// System messages count once, as they are sent as one system field.
+func (r Request) cacheMarks() map[int]bool {
+	var idx []int
+	sysMarked := false
+	for i, m := range r.Messages {
+		if !m.Cache {
+			continue
+		}
+		if m.Role == RoleSystem {
+			if sysMarked {
+				continue
+			}
+			sysMarked = true
+		}
+		idx = append(idx, i)
+	}
+	if len(idx) > maxCacheBreakpoints {
+		idx = idx[len(idx)-maxCacheBreakpoints:]
+	}
+	out := make(map[int]bool, len(idx))
+	for _, i := range idx {
+		out[i] = true
+	}
+	return out
+}
+
+// markCache puts a cache_control breakpoint on the last content block of an
+// Anthropic message, turning plain string content into a text block.
+func markCache(msg map[string]any) {
+	switch c := msg["content"].(type) {
+	case string:
+		msg["content"] = []any{map[string]any{"type": "text", "text": c, "cache_control": ephemeral}}
+	case []any:
+		if n := len(c); n > 0 {
+			if b, ok := c[n-1].(map[string]any); ok {
+				b["cache_control"] = ephemeral
+			}
+		}
+	}
+}
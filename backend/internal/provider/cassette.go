@@ -0,0 +1,207 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Cassette modes, selected with SWARMONE_CASSETTE.
+const (
+	CassetteRecord = "record" // pass through and save every exchange
+	CassetteReplay = "replay" // serve saved exchanges; never touch the network
+)
+
+// Cassette is an http.RoundTripper that records provider exchanges to Dir and
+// replays them offline. Exchanges are keyed by method, URL and normalized
+// JSON body (object keys sorted), with credentials scrubbed from headers and
+// the query string, so recordings are safe to commit. Each exchange is one
+// readable JSON file that can be edited by hand to reproduce odd responses.
+type Cassette struct {
+	Mode string
+	Dir  string
+	Next http.RoundTripper // used in record mode; nil → http.DefaultTransport
+}
+
+// cassetteFromEnv returns the cassette configured by SWARMONE_CASSETTE /
+// SWARMONE_CASSETTE_DIR (default "testdata/cassettes"), or nil when disabled.
+func cassetteFromEnv(next http.RoundTripper) *Cassette {
+	mode := strings.ToLower(strings.TrimSpace(os.Getenv("SWARMONE_CASSETTE")))
+	if mode != CassetteRecord && mode != CassetteReplay {
+		return nil
+	}
+	dir := firstNonEmpty(os.Getenv("SWARMONE_CASSETTE_DIR"), "testdata/cassettes")
+	return &Cassette{Mode: mode, Dir: dir, Next: next}
+}
+
+// newHTTPClient builds a client's default *http.Client on the provider's
+// shared transport, routed through the cassette when one is configured.
+func newHTTPClient(provider string, timeout time.Duration) *http.Client {
+	cl := &http.Client{Timeout: timeout, Transport: transportFor(provider)}
+	if c := cassetteFromEnv(cl.Transport); c != nil {
+		cl.Transport = c
+	}
+	return cl
+}
+
+// replaying reports whether clients run against recorded exchanges, in
+// which case missing API keys are not an error.
+func replaying() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv("SWARMONE_CASSETTE")), CassetteReplay)
+}
+
+type cassetteEntry struct {
+	Request struct {
+		Method string          `json:"method"`
+		URL    string          `json:"url"`
+		Body   json.RawMessage `json:"body,omitempty"`
+	} `json:"request"`
+	Response struct {
+		Status  int               `json:"status"`
+		Headers map[string]string `json:"headers,omitempty"`
+		JSON    json.RawMessage   `json:"json,omitempty"` // body, when it is valid JSON
+		Text    string            `json:"text,omitempty"` // body otherwise (e.g. SSE streams)
+	} `json:"response"`
+}
+
+// scrubbedHeaders never end up on disk: credentials, plus framing headers
+// that would go stale when a recording is edited by hand.
+var scrubbedHeaders = map[string]bool{
+	"authorization": true, "x-api-key": true, "x-goog-api-key": true, "api-key": true,
+	"cookie": true, "set-cookie": true, "openai-organization": true, "openai-project": true,
+	"content-length": true, "date": true, "transfer-encoding": true, "content-encoding": true,
+}
+
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+		req.Body = io.NopCloser(bytes.NewReader(b))
+	}
+	u := scrubURL(req.URL)
+	norm := normalizeJSON(body)
+	path := filepath.Join(c.Dir, cassetteName(req.Method, u, norm, req.URL.Host))
+
+	if c.Mode == CassetteReplay {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, &Error{Provider: "cassette", Kind: KindInvalidRequest, Message: "no recording for " + req.Method + " " + u + " (" + filepath.Base(path) + ")", Err: err}
+		}
+		var e cassetteEntry
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return nil, &Error{Provider: "cassette", Kind: KindBadResponse, Message: "corrupt recording " + path + ": " + err.Error(), Err: err}
+		}
+		return e.response(req), nil
+	}
+
+	next := c.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	var e cassetteEntry
+	e.Request.Method = req.Method
+	e.Request.URL = u
+	if json.Valid(norm) {
+		e.Request.Body = norm
+	}
+	e.Response.Status = resp.StatusCode
+	e.Response.Headers = map[string]string{}
+	for k := range resp.Header {
+		if !scrubbedHeaders[strings.ToLower(k)] {
+			e.Response.Headers[k] = resp.Header.Get(k)
+		}
+	}
+	if json.Valid(respBody) {
+		e.Response.JSON = respBody
+	} else {
+		e.Response.Text = string(respBody)
+	}
+	out, _ := json.MarshalIndent(e, "", "  ")
+	if err := os.MkdirAll(c.Dir, 0o755); err == nil {
+		_ = os.WriteFile(path, out, 0o644) // recording is best effort; the live response still goes through
+	}
+	return resp, nil
+}
+
+func (e cassetteEntry) response(req *http.Request) *http.Response {
+	body := []byte(e.Response.Text)
+	if len(e.Response.JSON) > 0 {
+		body = e.Response.JSON
+	}
+	h := http.Header{}
+	for k, v := range e.Response.Headers {
+		h.Set(k, v)
+	}
+	return &http.Response{
+		Status:        http.StatusText(e.Response.Status),
+		StatusCode:    e.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// scrubURL drops credentials from the query string (Gemini sends ?key=).
+func scrubURL(u *url.URL) string {
+	c := *u
+	q := c.Query()
+	for _, k := range []string{"key", "api_key", "access_token"} {
+		if q.Has(k) {
+			q.Set(k, "REDACTED")
+		}
+	}
+	c.RawQuery = q.Encode()
+	c.User = nil
+	return c.String()
+}
+
+// normalizeJSON re-encodes a JSON body with sorted keys and no insignificant
+// whitespace; non-JSON bodies are returned unchanged.
+func normalizeJSON(b []byte) []byte {
+	var v any
+	if len(bytes.TrimSpace(b)) == 0 || json.Unmarshal(b, &v) != nil {
+		return b
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return b
+	}
+	return out
+}
+
+func cassetteName(method, u string, body []byte, host string) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + u + "\n"))
+	h.Write(body)
+	sum := hex.EncodeToString(h.Sum(nil))[:16]
+	host = strings.NewReplacer(":", "_", "/", "_").Replace(host)
+	return host + "-" + sum + ".json"
+}
@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScrubURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://api.openai.com/v1/responses", "https://api.openai.com/v1/responses"},
+		{"https://g.example/v1/m:generateContent?key=SECRET", "https://g.example/v1/m:generateContent?key=REDACTED"},
+		{"https://g.example/v1/m:stream?alt=sse&key=SECRET", "https://g.example/v1/m:stream?alt=sse&key=REDACTED"},
+		{"https://x.example/?api_key=a&access_token=b&q=1", "https://x.example/?access_token=REDACTED&api_key=REDACTED&q=1"},
+		{"https://user:pw@x.example/path", "https://x.example/path"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := scrubURL(u); got != tt.want {
+			t.Errorf("scrubURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCassetteRecordScrubsAndReplays(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.Header().Set("X-Request-Id", "req-1")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"ok":true}`)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	send := func(mode, body string) *http.Response {
+		t.Helper()
+		hc := &http.Client{Transport: &Cassette{Mode: mode, Dir: dir, Next: http.DefaultTransport}}
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/v1/m:generateContent?key=SECRET", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer SECRET")
+		req.Header.Set("X-Api-Key", "SECRET")
+		resp, err := hc.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", mode, err)
+		}
+		return resp
+	}
+
+	resp := send(CassetteRecord, `{"b":1, "a":2}`)
+	resp.Body.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("recorded %d files, want 1", len(files))
+	}
+	raw, _ := os.ReadFile(files[0])
+	rec := string(raw)
+	for _, leak := range []string{"SECRET", "session=abc", "Set-Cookie"} {
+		if strings.Contains(rec, leak) {
+			t.Errorf("recording contains %q:\n%s", leak, rec)
+		}
+	}
+	if !strings.Contains(rec, "key=REDACTED") || !strings.Contains(rec, "req-1") {
+		t.Errorf("recording lost the redacted URL or a plain header:\n%s", rec)
+	}
+
+	// Replay ignores key order and spacing in the body and never hits the network.
+	srv.Close()
+	resp = send(CassetteReplay, `{"a":2,"b":1}`)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != 200 || string(normalizeJSON(body)) != `{"ok":true}` || resp.Header.Get("X-Request-Id") != "req-1" {
+		t.Errorf("replay = %d %s %v", resp.StatusCode, body, resp.Header)
+	}
+
+	hc := &http.Client{Transport: &Cassette{Mode: CassetteReplay, Dir: dir}}
+	if _, err := hc.Post(srv.URL+"/other", "application/json", strings.NewReader(`{}`)); err == nil {
+		t.Error("replay of an unrecorded request succeeded")
+	}
+}
@@ -0,0 +1,166 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ModelInfo is the catalog entry of one provider model: its limits, prices
+// and features. Prices are USD per million tokens; CachedPerMTok applies to
+// Usage.CachedTokens and CacheWritePerMTok to Usage.CacheWriteTokens (both
+// are part of InputTokens).
+type ModelInfo struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+
+	ContextWindow   int `json:"context_window"`    // input + output tokens
+	MaxOutputTokens int `json:"max_output_tokens"` // visible answer plus thinking
+
+	InputPerMTok      float64 `json:"input_per_mtok"`
+	OutputPerMTok     float64 `json:"output_per_mtok"`
+	CachedPerMTok     float64 `json:"cached_per_mtok,omitempty"`
+	CacheWritePerMTok float64 `json:"cache_write_per_mtok,omitempty"`
+
+	Tools      bool `json:"tools"`
+	JSONSchema bool `json:"json_schema"`
+	Vision     bool `json:"vision"`
+	Reasoning  bool `json:"reasoning"`
+}
+
+// Cost prices u in USD.
+func (m ModelInfo) Cost(u Usage) float64 {
+	cached, written := m.CachedPerMTok, m.CacheWritePerMTok
+	if cached == 0 {
+		cached = m.InputPerMTok
+	}
+	if written == 0 {
+		written = m.InputPerMTok
+	}
+	plain := u.InputTokens - u.CachedTokens - u.CacheWriteTokens
+	return (float64(plain)*m.InputPerMTok +
+		float64(u.CachedTokens)*cached +
+		float64(u.CacheWriteTokens)*written +
+		float64(u.OutputTokens)*m.OutputPerMTok) / 1e6
+}
+
+var catalog = struct {
+	sync.RWMutex
+	m map[string]ModelInfo // "provider/model" → info
+}{m: map[string]ModelInfo{}}
+
+func catalogKey(provider, model string) string {
+	if s, ok := Lookup(provider); ok {
+		provider = s.Name
+	}
+	return normName(provider) + "/" + strings.TrimSpace(model)
+}
+
+// RegisterModel adds m to the catalog, replacing any entry for the same
+// provider (name or alias) and model.
+func RegisterModel(m ModelInfo) error {
+	s, ok := Lookup(m.Provider)
+	if !ok {
+		return fmt.Errorf("model %q: unknown provider %q", m.Model, m.Provider)
+	}
+	if strings.TrimSpace(m.Model) == "" {
+		return fmt.Errorf("provider %s: model name missing", s.Name)
+	}
+	if m.ContextWindow < 0 || m.MaxOutputTokens < 0 {
+		return fmt.Errorf("%s/%s: negative token limit", s.Name, m.Model)
+	}
+	m.Provider = s.Name
+	catalog.Lock()
+	defer catalog.Unlock()
+	catalog.m[catalogKey(s.Name, m.Model)] = m
+	return nil
+}
+
+// LookupModel finds the catalog entry of a provider model. A dated or
+// suffixed model ("claude-sonnet-4-5-20250929") falls back to the longest
+// catalog name it extends ("claude-sonnet-4-5").
+func LookupModel(provider, model string) (ModelInfo, bool) {
+	key := catalogKey(provider, model)
+	catalog.RLock()
+	defer catalog.RUnlock()
+	if m, ok := catalog.m[key]; ok {
+		return m, true
+	}
+	var best ModelInfo
+	found := false
+	for k, m := range catalog.m {
+		if strings.HasPrefix(key, k+"-") && (!found || len(m.Model) > len(best.Model)) {
+			best, found = m, true
+		}
+	}
+	return best, found
+}
+
+// Models returns the catalog sorted by provider and model.
+func Models() []ModelInfo {
+	catalog.RLock()
+	defer catalog.RUnlock()
+	out := make([]ModelInfo, 0, len(catalog.m))
+	for _, m := range catalog.m {
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Provider != out[j].Provider {
+			return out[i].Provider < out[j].Provider
+		}
+		return out[i].Model < out[j].Model
+	})
+	return out
+}
+
+// ValidateMaxTokens checks a runner's output budget against the catalog:
+// maxTokens plus the thinking budget of r, as actually sent. Models not in
+// the catalog pass.
+func ValidateMaxTokens(provider, model string, maxTokens int, r *Reasoning) error {
+	m, ok := LookupModel(provider, model)
+	if !ok || maxTokens <= 0 {
+		return nil
+	}
+	out := r.outputBudget(maxTokens)
+	what := fmt.Sprintf("max_tokens %d", maxTokens)
+	if out != maxTokens {
+		what = fmt.Sprintf("max_tokens %d plus thinking budget %d", maxTokens, out-maxTokens)
+	}
+	if m.MaxOutputTokens > 0 && out > m.MaxOutputTokens {
+		return fmt.Errorf("%s exceeds %s/%s max output of %d", what, m.Provider, m.Model, m.MaxOutputTokens)
+	}
+	if m.ContextWindow > 0 && out >= m.ContextWindow {
+		return fmt.Errorf("%s leaves no room for input in the %d-token context of %s/%s", what, m.ContextWindow, m.Provider, m.Model)
+	}
+	return nil
+}
+
+// builtinModels is the built-in catalog, as published by the providers
+// (prices as of 2025). It is registered from builtin.go, after the providers.
+var builtinModels = []ModelInfo{
+	// OpenAI
+	{Provider: "openai", Model: "gpt-5", ContextWindow: 400000, MaxOutputTokens: 128000, InputPerMTok: 1.25, OutputPerMTok: 10, CachedPerMTok: 0.125, Tools: true, JSONSchema: true, Vision: true, Reasoning: true},
+	{Provider: "openai", Model: "gpt-5-mini", ContextWindow: 400000, MaxOutputTokens: 128000, InputPerMTok: 0.25, OutputPerMTok: 2, CachedPerMTok: 0.025, Tools: true, JSONSchema: true, Vision: true, Reasoning: true},
+	{Provider: "openai", Model: "gpt-5-nano", ContextWindow: 400000, MaxOutputTokens: 128000, InputPerMTok: 0.05, OutputPerMTok: 0.4, CachedPerMTok: 0.005, Tools: true, JSONSchema: true, Vision: true, Reasoning: true},
+	{Provider: "openai", Model: "gpt-4.1", ContextWindow: 1047576, MaxOutputTokens: 32768, InputPerMTok: 2, OutputPerMTok: 8, CachedPerMTok: 0.5, Tools: true, JSONSchema: true, Vision: true},
+	{Provider: "openai", Model: "gpt-4.1-mini", ContextWindow: 1047576, MaxOutputTokens: 32768, InputPerMTok: 0.4, OutputPerMTok: 1.6, CachedPerMTok: 0.1, Tools: true, JSONSchema: true, Vision: true},
+	{Provider: "openai", Model: "gpt-4o", ContextWindow: 128000, MaxOutputTokens: 16384, InputPerMTok: 2.5, OutputPerMTok: 10, CachedPerMTok: 1.25, Tools: true, JSONSchema: true, Vision: true},
+	{Provider: "openai", Model: "gpt-4o-mini", ContextWindow: 128000, MaxOutputTokens: 16384, InputPerMTok: 0.15, OutputPerMTok: 0.6, CachedPerMTok: 0.075, Tools: true, JSONSchema: true, Vision: true},
+	{Provider: "openai", Model: "o4-mini", ContextWindow: 200000, MaxOutputTokens: 100000, InputPerMTok: 1.1, OutputPerMTok: 4.4, CachedPerMTok: 0.275, Tools: true, JSONSchema: true, Vision: true, Reasoning: true},
+
+	// Gemini
+	{Provider: "gemini", Model: "gemini-2.5-pro", ContextWindow: 1048576, MaxOutputTokens: 65536, InputPerMTok: 1.25, OutputPerMTok: 10, CachedPerMTok: 0.31, Tools: true, JSONSchema: true, Vision: true, Reasoning: true},
+	{Provider: "gemini", Model: "gemini-2.5-flash", ContextWindow: 1048576, MaxOutputTokens: 65536, InputPerMTok: 0.3, OutputPerMTok: 2.5, CachedPerMTok: 0.075, Tools: true, JSONSchema: true, Vision: true, Reasoning: true},
+	{Provider: "gemini", Model: "gemini-2.5-flash-lite", ContextWindow: 1048576, MaxOutputTokens: 65536, InputPerMTok: 0.1, OutputPerMTok: 0.4, CachedPerMTok: 0.025, Tools: true, JSONSchema: true, Vision: true, Reasoning: true},
+	{Provider: "gemini", Model: "gemini-2.0-flash", ContextWindow: 1048576, MaxOutputTokens: 8192, InputPerMTok: 0.1, OutputPerMTok: 0.4, CachedPerMTok: 0.025, Tools: true, JSONSchema: true, Vision: true},
+
+	// Anthropic (cache writes are the 5-minute rate)
+	{Provider: "anthropic", Model: "claude-opus-4-1", ContextWindow: 200000, MaxOutputTokens: 32000, InputPerMTok: 15, OutputPerMTok: 75, CachedPerMTok: 1.5, CacheWritePerMTok: 18.75, Tools: true, JSONSchema: true, Vision: true, Reasoning: true},
+	{Provider: "anthropic", Model: "claude-sonnet-4-5", ContextWindow: 200000, MaxOutputTokens: 64000, InputPerMTok: 3, OutputPerMTok: 15, CachedPerMTok: 0.3, CacheWritePerMTok: 3.75, Tools: true, JSONSchema: true, Vision: true, Reasoning: true},
+	{Provider: "anthropic", Model: "claude-sonnet-4", ContextWindow: 200000, MaxOutputTokens: 64000, InputPerMTok: 3, OutputPerMTok: 15, CachedPerMTok: 0.3, CacheWritePerMTok: 3.75, Tools: true, JSONSchema: true, Vision: true, Reasoning: true},
+	{Provider: "anthropic", Model: "claude-haiku-4-5", ContextWindow: 200000, MaxOutputTokens: 64000, InputPerMTok: 1, OutputPerMTok: 5, CachedPerMTok: 0.1, CacheWritePerMTok: 1.25, Tools: true, JSONSchema: true, Vision: true, Reasoning: true},
+	{Provider: "anthropic", Model: "claude-3-7-sonnet", ContextWindow: 200000, MaxOutputTokens: 64000, InputPerMTok: 3, OutputPerMTok: 15, CachedPerMTok: 0.3, CacheWritePerMTok: 3.75, Tools: true, JSONSchema: true, Vision: true, Reasoning: true},
+	{Provider: "anthropic", Model: "claude-3-5-sonnet", ContextWindow: 200000, MaxOutputTokens: 8192, InputPerMTok: 3, OutputPerMTok: 15, CachedPerMTok: 0.3, CacheWritePerMTok: 3.75, Tools: true, JSONSchema: true, Vision: true},
+	{Provider: "anthropic", Model: "claude-3-5-haiku", ContextWindow: 200000, MaxOutputTokens: 8192, InputPerMTok: 0.8, OutputPerMTok: 4, CachedPerMTok: 0.08, CacheWritePerMTok: 1, Tools: true, JSONSchema: true},
+}
@@ -0,0 +1,31 @@
+package provider
+
+import "testing"
+
+func TestValidateMaxTokens(t *testing.T) {
+	budget := func(n int) *int { return &n }
+	tests := []struct {
+		name      string
+		provider  string
+		model     string
+		maxTokens int
+		reasoning *Reasoning
+		wantErr   bool
+	}{
+		{name: "within max output", provider: "anthropic", model: "claude-3-5-haiku-20241022", maxTokens: 4096},
+		{name: "over max output", provider: "anthropic", model: "claude-3-5-haiku-20241022", maxTokens: 9000, wantErr: true},
+		{name: "thinking pushes past max output", provider: "anthropic", model: "claude-3-5-haiku-20241022", maxTokens: 512, reasoning: &Reasoning{Effort: "high"}, wantErr: true},
+		{name: "thinking budget that fits", provider: "claude", model: "claude-3-5-haiku", maxTokens: 512, reasoning: &Reasoning{BudgetTokens: budget(4096)}},
+		{name: "dynamic thinking adds nothing", provider: "gemini", model: "gemini-2.0-flash", maxTokens: 8192, reasoning: &Reasoning{BudgetTokens: budget(-1)}},
+		{name: "no room for input", provider: "openai", model: "gpt-4o", maxTokens: 200000, wantErr: true},
+		{name: "unknown model passes", provider: "ollama", model: "llama3.1", maxTokens: 1 << 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMaxTokens(tt.provider, tt.model, tt.maxTokens, tt.reasoning)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMaxTokens = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// Embedder is implemented by clients that can turn texts into vectors
+// (Capabilities.Embeddings). The client's Model is the embedding model.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) (Embeddings, error)
+}
+
+// Embeddings holds one vector per input text, in input order.
+type Embeddings struct {
+	Vectors   [][]float32 `json:"vectors"`
+	Usage     Usage       `json:"usage"` // input tokens; not reported by Gemini
+	RequestID string      `json:"request_id,omitempty"`
+	Attempts  int         `json:"attempts"`
+}
+
+// Cosine returns the cosine similarity of two vectors, 0 when either is
+// zero or their lengths differ.
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		dot += x * y
+		na += x * x
+		nb += y * y
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// Embed calls POST /v1/embeddings.
+func (c *OpenAI) Embed(ctx context.Context, texts []string) (Embeddings, error) {
+	if c.Key == "" && !replaying() {
+		return Embeddings{}, newError("openai", KindAuth, "api key missing")
+	}
+	c.ensureHTTP()
+	return embedOpenAI(ctx, "openai", c.HTTP, c.Retry, "https://api.openai.com/v1/embeddings", c.Model, texts, func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+c.Key)
+	})
+}
+
+// Embed calls POST {BaseURL}/embeddings, which vLLM, llama.cpp (--embeddings)
+// and LiteLLM serve for embedding models.
+func (c *OpenAICompat) Embed(ctx context.Context, texts []string) (Embeddings, error) {
+	if strings.TrimSpace(c.BaseURL) == "" {
+		return Embeddings{}, newError("openai-compatible", KindInvalidRequest, "base url missing")
+	}
+	c.ensureHTTP()
+	url := strings.TrimRight(c.BaseURL, "/") + "/embeddings"
+	return embedOpenAI(ctx, "openai-compatible", c.HTTP, c.Retry, url, c.Model, texts, func(req *http.Request) {
+		if c.Key != "" {
+			req.Header.Set("Authorization", "Bearer "+c.Key)
+		}
+		for k, v := range c.Headers {
+			req.Header.Set(k, v)
+		}
+	})
+}
+
+// embedOpenAI speaks the OpenAI embeddings format shared by both clients.
+func embedOpenAI(ctx context.Context, name string, hc *http.Client, retry RetryPolicy, url, model string, texts []string, auth func(*http.Request)) (Embeddings, error) {
+	b, _ := json.Marshal(map[string]any{"model": model, "input": texts, "encoding_format": "float"})
+	resp, attempts, err := retry.do(ctx, hc, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		auth(req)
+		return req, nil
+	})
+	if err != nil {
+		return Embeddings{Attempts: attempts}, transportError(ctx, name, err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Embeddings{Attempts: attempts}, httpError(name, resp.StatusCode, raw)
+	}
+	var jr struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(raw, &jr); err != nil {
+		return Embeddings{Attempts: attempts}, newError(name, KindBadResponse, "decode error: %v; body=%s", err, string(raw))
+	}
+	out := Embeddings{
+		Vectors:   make([][]float32, len(texts)),
+		Usage:     Usage{InputTokens: jr.Usage.PromptTokens},
+		RequestID: resp.Header.Get("x-request-id"),
+		Attempts:  attempts,
+	}
+	for _, d := range jr.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return out, newError(name, KindBadResponse, "embedding index %d out of range", d.Index)
+		}
+		out.Vectors[d.Index] = d.Embedding
+	}
+	return out, checkVectors(name, out.Vectors)
+}
+
+// geminiEmbedBatch is the batchEmbedContents request limit.
+const geminiEmbedBatch = 100
+
+// Embed calls :batchEmbedContents, in batches of 100 texts.
+func (g *Gemini) Embed(ctx context.Context, texts []string) (Embeddings, error) {
+	if g.Key == "" && !replaying() {
+		return Embeddings{}, newError("gemini", KindAuth, "api key missing")
+	}
+	g.ensureHTTP()
+
+	model := strings.TrimPrefix(g.Model, "models/")
+	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:batchEmbedContents?key=%s", model, g.Key)
+	var out Embeddings
+	for start := 0; start < len(texts); start += geminiEmbedBatch {
+		batch := texts[start:min(start+geminiEmbedBatch, len(texts))]
+		reqs := make([]map[string]any, 0, len(batch))
+		for _, t := range batch {
+			reqs = append(reqs, map[string]any{
+				"model":   "models/" + model,
+				"content": map[string]any{"parts": []any{map[string]any{"text": t}}},
+			})
+		}
+		resp, attempts, err := g.Retry.do(ctx, g.HTTP, func() (*http.Request, error) {
+			return g.newRequest(ctx, url, map[string]any{"requests": reqs})
+		})
+		out.Attempts += attempts
+		if err != nil {
+			return out, transportError(ctx, "gemini", err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return out, httpError("gemini", resp.StatusCode, raw)
+		}
+		var jr struct {
+			Embeddings []struct {
+				Values []float32 `json:"values"`
+			} `json:"embeddings"`
+		}
+		if err := json.Unmarshal(raw, &jr); err != nil {
+			return out, newError("gemini", KindBadResponse, "decode error: %v; body=%s", err, string(raw))
+		}
+		if len(jr.Embeddings) != len(batch) {
+			return out, newError("gemini", KindBadResponse, "got %d embeddings for %d texts", len(jr.Embeddings), len(batch))
+		}
+		for _, e := range jr.Embeddings {
+			out.Vectors = append(out.Vectors, e.Values)
+		}
+	}
+	return out, checkVectors("gemini", out.Vectors)
+}
+
+// Embed calls POST /api/embed, which takes a batch of inputs.
+func (o *Ollama) Embed(ctx context.Context, texts []string) (Embeddings, error) {
+	o.ensureHTTP()
+	b, _ := json.Marshal(map[string]any{"model": o.Model, "input": texts})
+	resp, attempts, err := o.Retry.do(ctx, o.HTTP, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL()+"/api/embed", bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return Embeddings{Attempts: attempts}, transportError(ctx, "ollama", err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Embeddings{Attempts: attempts}, httpError("ollama", resp.StatusCode, raw)
+	}
+	var jr struct {
+		Embeddings      [][]float32 `json:"embeddings"`
+		PromptEvalCount int         `json:"prompt_eval_count"`
+	}
+	if err := json.Unmarshal(raw, &jr); err != nil {
+		return Embeddings{Attempts: attempts}, newError("ollama", KindBadResponse, "decode error: %v; body=%s", err, string(raw))
+	}
+	if len(jr.Embeddings) != len(texts) {
+		return Embeddings{Attempts: attempts}, newError("ollama", KindBadResponse, "got %d embeddings for %d texts", len(jr.Embeddings), len(texts))
+	}
+	out := Embeddings{Vectors: jr.Embeddings, Usage: Usage{InputTokens: jr.PromptEvalCount}, Attempts: attempts}
+	return out, checkVectors("ollama", out.Vectors)
+}
+
+// mockEmbedDims is the size of mock vectors.
+const mockEmbedDims = 16
+
+// Embed returns deterministic unit vectors hashed from the lower-cased words
+// of each text, so texts sharing words are similar. No fixture is needed.
+func (m *Mock) Embed(ctx context.Context, texts []string) (Embeddings, error) {
+	out := Embeddings{Attempts: 1}
+	for _, t := range texts {
+		v := make([]float32, mockEmbedDims)
+		for _, w := range strings.Fields(strings.ToLower(t)) {
+			h := fnv.New32a()
+			h.Write([]byte(w))
+			v[h.Sum32()%mockEmbedDims]++
+		}
+		var norm float64
+		for _, x := range v {
+			norm += float64(x * x)
+		}
+		if norm > 0 {
+			for i := range v {
+				v[i] = float32(float64(v[i]) / math.Sqrt(norm))
+			}
+		}
+		out.Vectors = append(out.Vectors, v)
+		out.Usage.InputTokens += CountTokens("mock", t)
+	}
+	return out, ctx.Err()
+}
+
+// checkVectors rejects a response with a missing vector.
+func checkVectors(name string, vs [][]float32) error {
+	for i, v := range vs {
+		if len(v) == 0 {
+			return newError(name, KindBadResponse, "no embedding for input %d", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrorKind classifies provider failures so callers can react without parsing messages.
+type ErrorKind string
+
+const (
+	KindAuth             ErrorKind = "auth"              // bad/missing key, forbidden
+	KindRateLimit        ErrorKind = "rate_limit"        // 429 / quota
+	KindSafety           ErrorKind = "safety"            // blocked by the provider's safety system
+	KindContextLength    ErrorKind = "context_length"    // prompt (+ max tokens) exceeds the context window
+	KindInvalidRequest   ErrorKind = "invalid_request"   // other 4xx: bad params, unknown model, ...
+	KindTimeout          ErrorKind = "timeout"           // deadline exceeded, 408/504
+	KindServer           ErrorKind = "server"            // 5xx, overloaded
+	KindNetwork          ErrorKind = "network"           // connection-level failures
+	KindCanceled         ErrorKind = "canceled"          // caller canceled the context
+	KindEmptyOutput      ErrorKind = "empty_output"      // call succeeded but produced no text
+	KindBadResponse      ErrorKind = "bad_response"      // undecodable response body / stream
+	KindUnsupportedInput ErrorKind = "unsupported_input" // attachment modality the provider cannot take
+	KindUnknown          ErrorKind = "unknown"
+)
+
+// Sentinels for errors.Is; every *Error matches the sentinel of its Kind.
+var (
+	ErrAuth             = errors.New("provider: auth")
+	ErrRateLimit        = errors.New("provider: rate limit")
+	ErrSafety           = errors.New("provider: safety block")
+	ErrContextLength    = errors.New("provider: context length exceeded")
+	ErrInvalidRequest   = errors.New("provider: invalid request")
+	ErrTimeout          = errors.New("provider: timeout")
+	ErrServer           = errors.New("provider: server error")
+	ErrNetwork          = errors.New("provider: network error")
+	ErrCanceled         = errors.New("provider: canceled")
+	ErrEmptyOutput      = errors.New("provider: empty output")
+	ErrBadResponse      = errors.New("provider: bad response")
+	ErrUnsupportedInput = errors.New("provider: unsupported input")
+)
+
+var kindSentinels = map[ErrorKind]error{
+	KindAuth:             ErrAuth,
+	KindRateLimit:        ErrRateLimit,
+	KindSafety:           ErrSafety,
+	KindContextLength:    ErrContextLength,
+	KindInvalidRequest:   ErrInvalidRequest,
+	KindTimeout:          ErrTimeout,
+	KindServer:           ErrServer,
+	KindNetwork:          ErrNetwork,
+	KindCanceled:         ErrCanceled,
+	KindEmptyOutput:      ErrEmptyOutput,
+	KindBadResponse:      ErrBadResponse,
+	KindUnsupportedInput: ErrUnsupportedInput,
+}
+
+// Error is the error type returned by all clients in this package.
+type Error struct {
+	Provider  string    // "openai", "anthropic", ...
+	Kind      ErrorKind //
+	Status    int       // HTTP status; 0 when no response was received
+	Code      string    // provider error code/type, e.g. "rate_limit_error", "RESOURCE_EXHAUSTED"
+	Message   string    //
+	Retryable bool      // whether repeating the same request may succeed
+	Err       error     // underlying cause, if any
+}
+
+func (e *Error) Error() string {
+	if e.Status > 0 {
+		return fmt.Sprintf("%s http %d: %s", e.Provider, e.Status, e.Message)
+	}
+	if e.Provider == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s %s", e.Provider, e.Message)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+func (e *Error) Is(target error) bool {
+	s, ok := kindSentinels[e.Kind]
+	return ok && s == target
+}
+
+// AsError extracts the *Error from err's chain, or wraps err as KindUnknown.
+func AsError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var pe *Error
+	if errors.As(err, &pe) {
+		return pe
+	}
+	return &Error{Kind: KindUnknown, Message: err.Error(), Err: err}
+}
+
+// httpError builds an *Error from a non-2xx response, decoding the common
+// error envelopes of OpenAI, Anthropic, Gemini and Ollama.
+func httpError(provider string, status int, body []byte) *Error {
+	code, msg := parseErrorBody(body)
+	if msg == "" {
+		msg = strings.TrimSpace(string(body))
+	}
+	e := &Error{
+		Provider:  provider,
+		Status:    status,
+		Code:      code,
+		Message:   msg,
+		Retryable: retryableStatus(status),
+	}
+	lower := strings.ToLower(code + " " + msg)
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden,
+		strings.Contains(lower, "api key not valid"), strings.Contains(lower, "api_key_invalid"):
+		e.Kind = KindAuth
+	case status == http.StatusTooManyRequests || code == "RESOURCE_EXHAUSTED":
+		e.Kind = KindRateLimit
+		if strings.Contains(lower, "insufficient_quota") {
+			e.Retryable = false // billing problem, not a transient limit
+		}
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		e.Kind = KindTimeout
+	case status >= 500:
+		e.Kind = KindServer
+	case isContextLengthMessage(lower):
+		e.Kind = KindContextLength
+	case status == http.StatusBadRequest && strings.Contains(lower, "safety"):
+		e.Kind = KindSafety
+	case status >= 400:
+		e.Kind = KindInvalidRequest
+	default:
+		e.Kind = KindUnknown
+	}
+	return e
+}
+
+func isContextLengthMessage(lower string) bool {
+	for _, s := range []string{
+		"context_length_exceeded",
+		"maximum context length",
+		"context window",
+		"prompt is too long",
+		"input is too long",
+		"exceeds the maximum number of tokens",
+		"input token count",
+		"too many tokens",
+	} {
+		if strings.Contains(lower, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseErrorBody returns (code, message) from the provider error envelopes:
+//
+//	OpenAI:    {"error":{"message":..,"type":..,"code":..}}
+//	Anthropic: {"type":"error","error":{"type":..,"message":..}}
+//	Gemini:    {"error":{"code":429,"message":..,"status":"RESOURCE_EXHAUSTED"}}
+//	Ollama:    {"error":"..."}
+func parseErrorBody(body []byte) (string, string) {
+	var env struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &env) != nil || len(env.Error) == 0 {
+		return "", ""
+	}
+	var s string
+	if json.Unmarshal(env.Error, &s) == nil {
+		return "", s
+	}
+	var e struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    any    `json:"code"`
+		Status  string `json:"status"`
+	}
+	if json.Unmarshal(env.Error, &e) != nil {
+		return "", ""
+	}
+	code := e.Status
+	if c, ok := e.Code.(string); ok && code == "" {
+		code = c
+	}
+	if code == "" {
+		code = e.Type
+	}
+	return code, e.Message
+}
+
+// streamError classifies an error event received in the middle of a stream,
+// where only the provider's error code/type and message are available.
+func streamError(provider, code, msg string) *Error {
+	e := &Error{Provider: provider, Code: code, Message: "stream error: " + msg}
+	lower := strings.ToLower(code + " " + msg)
+	switch {
+	case strings.Contains(lower, "auth") || strings.Contains(lower, "permission"):
+		e.Kind = KindAuth
+	case strings.Contains(lower, "rate_limit") || strings.Contains(lower, "resource_exhausted"):
+		e.Kind, e.Retryable = KindRateLimit, true
+	case isContextLengthMessage(lower):
+		e.Kind = KindContextLength
+	case strings.Contains(lower, "invalid_request") || strings.Contains(lower, "not_found"):
+		e.Kind = KindInvalidRequest
+	default: // overloaded_error, api_error, server_error, ...
+		e.Kind, e.Retryable = KindServer, true
+	}
+	return e
+}
+
+// transportError classifies a failure that produced no HTTP response.
+// Errors that already are *Error pass through unchanged.
+func transportError(ctx context.Context, provider string, err error) error {
+	var pe *Error
+	if errors.As(err, &pe) {
+		return err
+	}
+	e := &Error{Provider: provider, Message: redactURLError(err), Err: err}
+	var ne net.Error
+	switch {
+	case errors.Is(err, context.Canceled) && ctx.Err() == context.Canceled:
+		e.Kind = KindCanceled
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &ne) && ne.Timeout()):
+		e.Kind, e.Retryable = KindTimeout, true
+	default:
+		e.Kind, e.Retryable = KindNetwork, true
+	}
+	return e
+}
+
+// redactURLError returns err's text with the request URL stripped of its
+// query string and user info: Gemini sends the API key as ?key=, and a
+// *url.Error quotes the full URL.
+func redactURLError(err error) string {
+	var ue *url.Error
+	if !errors.As(err, &ue) {
+		return err.Error()
+	}
+	u, perr := url.Parse(ue.URL)
+	if perr != nil {
+		return ue.Op + ": " + ue.Err.Error()
+	}
+	u.RawQuery, u.ForceQuery, u.User = "", false, nil
+	return (&url.Error{Op: ue.Op, URL: u.String(), Err: ue.Err}).Error()
+}
+
+// newError builds an *Error that did not come from an HTTP status.
+func newError(provider string, kind ErrorKind, format string, args ...any) *Error {
+	return &Error{
+		Provider:  provider,
+		Kind:      kind,
+		Message:   fmt.Sprintf(format, args...),
+		Retryable: kind == KindServer || kind == KindTimeout || kind == KindNetwork,
+	}
+}
@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHTTPError(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		wantKind  ErrorKind
+		wantCode  string
+		wantMsg   string
+		retryable bool
+	}{
+		{
+			name: "openai auth", status: 401,
+			body:     `{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`,
+			wantKind: KindAuth, wantCode: "invalid_api_key", wantMsg: "Incorrect API key provided",
+		},
+		{
+			name: "gemini bad key on 400", status: 400,
+			body:     `{"error":{"code":400,"message":"API key not valid. Please pass a valid API key.","status":"INVALID_ARGUMENT"}}`,
+			wantKind: KindAuth, wantCode: "INVALID_ARGUMENT",
+		},
+		{
+			name: "anthropic rate limit", status: 429,
+			body:     `{"type":"error","error":{"type":"rate_limit_error","message":"Number of requests has exceeded your rate limit"}}`,
+			wantKind: KindRateLimit, wantCode: "rate_limit_error", retryable: true,
+		},
+		{
+			name: "gemini quota without 429", status: 400,
+			body:     `{"error":{"code":400,"message":"quota","status":"RESOURCE_EXHAUSTED"}}`,
+			wantKind: KindRateLimit, wantCode: "RESOURCE_EXHAUSTED",
+		},
+		{
+			name: "openai billing quota is not retryable", status: 429,
+			body:     `{"error":{"message":"You exceeded your current quota","type":"insufficient_quota","code":"insufficient_quota"}}`,
+			wantKind: KindRateLimit, wantCode: "insufficient_quota",
+		},
+		{name: "gateway timeout", status: 504, body: `upstream timed out`, wantKind: KindTimeout, wantMsg: "upstream timed out", retryable: true},
+		{name: "anthropic overloaded", status: 529, body: `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, wantKind: KindServer, wantCode: "overloaded_error", retryable: true},
+		{name: "server error", status: 500, body: `{"error":"boom"}`, wantKind: KindServer, wantMsg: "boom", retryable: true},
+		{
+			name: "context length", status: 400,
+			body:     `{"error":{"message":"This model's maximum context length is 128000 tokens","type":"invalid_request_error","code":"context_length_exceeded"}}`,
+			wantKind: KindContextLength, wantCode: "context_length_exceeded",
+		},
+		{name: "anthropic prompt too long", status: 400, body: `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`, wantKind: KindContextLength},
+		{name: "safety", status: 400, body: `{"error":{"message":"Request blocked by safety filters"}}`, wantKind: KindSafety},
+		{name: "other 4xx", status: 404, body: `{"error":{"message":"model not found","type":"not_found_error"}}`, wantKind: KindInvalidRequest, wantCode: "not_found_error"},
+		{name: "ollama string error", status: 400, body: `{"error":"invalid options"}`, wantKind: KindInvalidRequest, wantMsg: "invalid options"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := httpError("p", tt.status, []byte(tt.body))
+			if e.Kind != tt.wantKind {
+				t.Errorf("Kind = %q, want %q", e.Kind, tt.wantKind)
+			}
+			if tt.wantCode != "" && e.Code != tt.wantCode {
+				t.Errorf("Code = %q, want %q", e.Code, tt.wantCode)
+			}
+			if tt.wantMsg != "" && e.Message != tt.wantMsg {
+				t.Errorf("Message = %q, want %q", e.Message, tt.wantMsg)
+			}
+			if e.Retryable != tt.retryable {
+				t.Errorf("Retryable = %v, want %v", e.Retryable, tt.retryable)
+			}
+			if e.Status != tt.status {
+				t.Errorf("Status = %d, want %d", e.Status, tt.status)
+			}
+			if s, ok := kindSentinels[tt.wantKind]; ok && !errors.Is(e, s) {
+				t.Errorf("errors.Is(err, %v) = false", s)
+			}
+		})
+	}
+}
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "i/o timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+func TestTransportError(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	bg := context.Background()
+	typed := &Error{Provider: "openai", Kind: KindAuth}
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		err       error
+		wantKind  ErrorKind
+		retryable bool
+	}{
+		{name: "caller canceled", ctx: canceled, err: context.Canceled, wantKind: KindCanceled},
+		{name: "canceled without caller cancel is network", ctx: bg, err: context.Canceled, wantKind: KindNetwork, retryable: true},
+		{name: "deadline", ctx: bg, err: context.DeadlineExceeded, wantKind: KindTimeout, retryable: true},
+		{name: "net timeout", ctx: bg, err: &url.Error{Op: "Post", URL: "https://api.example.com", Err: timeoutErr{}}, wantKind: KindTimeout, retryable: true},
+		{name: "connection refused", ctx: bg, err: errors.New("dial tcp: connection refused"), wantKind: KindNetwork, retryable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pe := AsError(transportError(tt.ctx, "p", tt.err))
+			if pe.Kind != tt.wantKind || pe.Retryable != tt.retryable {
+				t.Errorf("got kind %q retryable %v, want %q %v", pe.Kind, pe.Retryable, tt.wantKind, tt.retryable)
+			}
+			if !errors.Is(pe, tt.err) {
+				t.Errorf("cause %v not kept in the chain", tt.err)
+			}
+		})
+	}
+
+	if got := transportError(bg, "p", typed); got != error(typed) {
+		t.Errorf("typed error was rewrapped: %v", got)
+	}
+}
+
+func TestTransportErrorRedactsURL(t *testing.T) {
+	err := &url.Error{
+		Op:  "Post",
+		URL: "https://user:pw@generativelanguage.googleapis.com/v1beta/models/gemini-2.5-flash:generateContent?alt=sse&key=SECRET",
+		Err: errors.New("dial tcp: connection refused"),
+	}
+	msg := transportError(context.Background(), "gemini", err).Error()
+	for _, leak := range []string{"SECRET", "key=", "pw@"} {
+		if strings.Contains(msg, leak) {
+			t.Errorf("message %q contains %q", msg, leak)
+		}
+	}
+	if !strings.Contains(msg, "generativelanguage.googleapis.com/v1beta/models/gemini-2.5-flash:generateContent") || !strings.Contains(msg, "connection refused") {
+		t.Errorf("message %q lost the endpoint or cause", msg)
+	}
+}
@@ -0,0 +1,158 @@
+package provider
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Local token estimates, used before a call for rate limits and context
+// window checks. CountTokens approximates a BPE tokenizer: words cost about
+// one token per charsPerToken letters, digits go in groups of three,
+// punctuation and line breaks are a token each, a single space before a word
+// is free, and CJK and similar scripts cost a token per character. It
+// tracks real tokenizers within ~10–15% on prose and leans high on code.
+const (
+	messageOverhead  = 4    // role and framing tokens per message
+	imageTokens      = 1000 // a typical image after provider downscaling
+	documentPageSize = 3000 // PDF bytes per estimated page
+	pageTokens       = 1500 // text plus page image for a PDF page
+)
+
+// charsPerToken is the average word length per token of each provider's
+// tokenizer; unknown providers (self-hosted models with smaller
+// vocabularies) get the conservative default.
+var charsPerToken = map[string]float64{
+	"openai":    4.2,
+	"gemini":    4.0,
+	"anthropic": 3.6,
+}
+
+const defaultCharsPerToken = 3.4
+
+func tokenRatio(provider string) float64 {
+	if s, ok := Lookup(provider); ok {
+		if r, ok := charsPerToken[s.Name]; ok {
+			return r
+		}
+	}
+	return defaultCharsPerToken
+}
+
+// CountTokens estimates the tokens of s for the provider's tokenizer.
+func CountTokens(provider, s string) int {
+	ratio := tokenRatio(provider)
+	n := 0.0
+	word, digits := 0, 0
+	flush := func() {
+		if word > 0 {
+			n += max(1, float64(word)/ratio)
+		}
+		if digits > 0 {
+			n += float64((digits + 2) / 3)
+		}
+		word, digits = 0, 0
+	}
+	prevSpace := false
+	for _, r := range s {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			flush()
+			n++
+		case unicode.IsLetter(r) || unicode.IsMark(r):
+			if digits > 0 {
+				flush()
+			}
+			word++
+		case unicode.IsDigit(r):
+			if word > 0 {
+				flush()
+			}
+			digits++
+		case r == ' ':
+			flush()
+			if prevSpace {
+				n++ // runs of spaces (indentation) are not free
+			}
+		default: // punctuation, symbols, other whitespace
+			flush()
+			n++
+		}
+		prevSpace = r == ' '
+	}
+	flush()
+	return int(n + 0.5)
+}
+
+// EstimateInputTokens estimates the prompt tokens of r for a provider:
+// messages, tool declarations, the output schema and attachments.
+func EstimateInputTokens(provider string, r Request) int {
+	n := 0
+	for _, m := range r.Messages {
+		n += messageOverhead + CountTokens(provider, m.Content)
+		for _, tc := range m.ToolCalls {
+			n += CountTokens(provider, tc.Name) + CountTokens(provider, string(tc.Arguments))
+		}
+		for _, a := range m.Attachments {
+			if a.Modality() == ModalityDocument {
+				n += (len(a.Data)/documentPageSize + 1) * pageTokens
+			} else {
+				n += imageTokens
+			}
+		}
+	}
+	for _, t := range r.Tools {
+		n += CountTokens(provider, t.Name+" "+t.Description) + CountTokens(provider, string(t.schema()))
+	}
+	if r.Output != nil {
+		n += CountTokens(provider, string(r.Output.Schema))
+	}
+	return n
+}
+
+// EstimateTokens is the most a call of r can use: the estimated input plus
+// the full output budget. This is what counts against a tokens-per-minute
+// limit or a context window.
+func EstimateTokens(provider string, r Request) int {
+	return EstimateInputTokens(provider, r) + r.OutputBudget()
+}
+
+// OutputBudget is the output tokens r may produce: MaxTokens plus the
+// thinking budget.
+func (r Request) OutputBudget() int {
+	return r.Reasoning.outputBudget(r.MaxTokens)
+}
+
+// TruncateMiddle shortens s to about maxTokens (provider estimate) by
+// cutting out its middle, where long inputs usually matter least, and
+// marking the cut. It returns s unchanged when it already fits, and the
+// estimated number of tokens removed.
+func TruncateMiddle(provider, s string, maxTokens int) (string, int) {
+	total := CountTokens(provider, s)
+	if total <= maxTokens {
+		return s, 0
+	}
+	keep := int(float64(utf8.RuneCountInString(s)) * float64(maxTokens) / float64(total))
+	for {
+		head, tail := runeEnds(s, keep)
+		cut := head + "\n\n[... truncated ...]\n\n" + tail
+		if n := CountTokens(provider, cut); n <= maxTokens || keep == 0 {
+			return cut, total - n
+		}
+		keep = keep * 9 / 10
+	}
+}
+
+// runeEnds returns the first and last runes of s, n in total.
+func runeEnds(s string, n int) (head, tail string) {
+	i := 0
+	for k := 0; k < n/2 && i < len(s); k++ {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	j := len(s)
+	for k := 0; k < n-n/2 && j > i; k++ {
+		_, size := utf8.DecodeLastRuneInString(s[:j])
+		j -= size
+	}
+	return s[:i], s[j:]
+}
@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCountTokens(t *testing.T) {
+	tests := []struct {
+		provider string
+		s        string
+		want     int
+	}{
+		{"openai", "", 0},
+		{"openai", "hello", 1},
+		{"openai", "hello world", 2}, // "hello" 1.19 + "world" 1.19, rounded
+		{"openai", "12345", 2},       // digits in groups of three
+		{"openai", "a, b.", 4},       // punctuation is a token each
+		{"openai", "你好世界", 4},        // one token per CJK character
+		{"openai", "    x", 4},       // indentation is not free
+	}
+	for _, tt := range tests {
+		if got := CountTokens(tt.provider, tt.s); got != tt.want {
+			t.Errorf("CountTokens(%q, %q) = %d, want %d", tt.provider, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateMiddle(t *testing.T) {
+	long := strings.Repeat("alpha beta gamma delta. ", 200) + "THE END"
+	cjk := strings.Repeat("长文本测试", 200)
+	tests := []struct {
+		name      string
+		s         string
+		maxTokens int
+		unchanged bool
+		onlyMark  bool // budget below the marker's own cost
+	}{
+		{name: "fits", s: "short text", maxTokens: 100, unchanged: true},
+		{name: "exactly at the limit", s: "one two three", maxTokens: CountTokens("openai", "one two three"), unchanged: true},
+		{name: "long prose", s: long, maxTokens: 120},
+		{name: "cjk stays valid utf-8", s: cjk, maxTokens: 50},
+		{name: "tiny budget", s: long, maxTokens: 4, onlyMark: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, removed := TruncateMiddle("openai", tt.s, tt.maxTokens)
+			if tt.unchanged {
+				if got != tt.s || removed != 0 {
+					t.Fatalf("TruncateMiddle changed a fitting text: %q, removed %d", got, removed)
+				}
+				return
+			}
+			if !strings.Contains(got, "[... truncated ...]") {
+				t.Fatalf("no truncation marker in %q", got)
+			}
+			if tt.onlyMark {
+				if strings.TrimSpace(got) != "[... truncated ...]" {
+					t.Errorf("result = %q, want only the marker", got)
+				}
+				return
+			}
+			if n := CountTokens("openai", got); n > tt.maxTokens {
+				t.Errorf("result has %d tokens, want <= %d", n, tt.maxTokens)
+			}
+			if want := CountTokens("openai", tt.s) - CountTokens("openai", got); removed != want {
+				t.Errorf("removed = %d, want %d", removed, want)
+			}
+			if !utf8.ValidString(got) {
+				t.Error("result is not valid UTF-8")
+			}
+			head, tail, _ := strings.Cut(got, "\n\n[... truncated ...]\n\n")
+			if !strings.HasPrefix(tt.s, head) || !strings.HasSuffix(tt.s, tail) {
+				t.Errorf("result does not keep the ends: head %q tail %q", head, tail)
+			}
+		})
+	}
+}
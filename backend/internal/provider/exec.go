@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Exec runs a local command per request: the request goes to stdin as JSON
+// (ExecInput), the answer comes back on stdout as JSON (ExecOutput). The
+// process is killed when the context is canceled or Timeout passes. Any
+// rule-based system, script or inference binary that speaks this protocol
+// can sit in the swarm next to the LLMs.
+type Exec struct {
+	Model   string
+	Command []string          // argv; Command[0] is looked up in $PATH
+	Env     map[string]string // added to the server's environment
+	Dir     string            // working directory; default the server's
+	Timeout time.Duration     // default 60s
+}
+
+// ExecInput is written to the command's stdin.
+type ExecInput struct {
+	Model string `json:"model"`
+	Request
+}
+
+// ExecOutput is read from the command's stdout. Error, when set, fails the
+// call with the given kind (default "server").
+type ExecOutput struct {
+	Text         string     `json:"text"`
+	FinishReason string     `json:"finish_reason,omitempty"`
+	Usage        *Usage     `json:"usage,omitempty"`
+	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
+	Error        *struct {
+		Kind      ErrorKind `json:"kind"`
+		Message   string    `json:"message"`
+		Retryable bool      `json:"retryable"`
+	} `json:"error,omitempty"`
+}
+
+func NewExec(model string, command []string, env map[string]string, dir string, timeout time.Duration) Client {
+	return &Exec{Model: model, Command: command, Env: env, Dir: dir, Timeout: timeout}
+}
+
+func (x *Exec) Generate(ctx context.Context, r Request) (Result, error) {
+	if len(x.Command) == 0 || strings.TrimSpace(x.Command[0]) == "" {
+		return Result{}, newError("exec", KindInvalidRequest, "command missing")
+	}
+	timeout := x.Timeout
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	cctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	in, err := json.Marshal(ExecInput{Model: x.Model, Request: r})
+	if err != nil {
+		return Result{}, newError("exec", KindInvalidRequest, "encode input: %v", err)
+	}
+	cmd := exec.CommandContext(cctx, x.Command[0], x.Command[1:]...)
+	cmd.Dir = x.Dir
+	if len(x.Env) > 0 {
+		cmd.Env = os.Environ()
+		for k, v := range x.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+	// Don't wait forever on pipes held open by grandchildren after a kill.
+	cmd.WaitDelay = 2 * time.Second
+	cmd.Stdin = bytes.NewReader(in)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	res := Result{Attempts: 1}
+	runErr := cmd.Run()
+	switch {
+	case ctx.Err() != nil:
+		return res, transportError(ctx, "exec", ctx.Err())
+	case cctx.Err() != nil:
+		return res, newError("exec", KindTimeout, "%s timed out after %s", x.Command[0], timeout)
+	case runErr != nil:
+		var ee *exec.ExitError
+		if !errors.As(runErr, &ee) {
+			// Could not start: bad path, permissions, ...
+			return res, newError("exec", KindInvalidRequest, "%v", runErr)
+		}
+		return res, newError("exec", KindServer, "%s exited with %d: %s",
+			x.Command[0], ee.ExitCode(), truncateTail(stderr.String(), 500))
+	}
+
+	var out ExecOutput
+	if err := json.Unmarshal(bytes.TrimSpace(stdout.Bytes()), &out); err != nil {
+		return res, newError("exec", KindBadResponse, "stdout is not JSON: %v; stdout=%s; stderr=%s",
+			err, truncateTail(stdout.String(), 300), truncateTail(stderr.String(), 300))
+	}
+	if e := out.Error; e != nil {
+		kind := e.Kind
+		if kind == "" {
+			kind = KindServer
+		}
+		return res, &Error{Provider: "exec", Kind: kind, Message: firstNonEmpty(e.Message, "command reported an error"), Retryable: e.Retryable}
+	}
+	res.Text = strings.TrimSpace(out.Text)
+	res.FinishReason = firstNonEmpty(out.FinishReason, "stop")
+	res.ToolCalls = out.ToolCalls
+	if out.Usage != nil {
+		res.Usage = *out.Usage
+	}
+	if res.Text == "" && len(res.ToolCalls) == 0 {
+		return res, newError("exec", KindEmptyOutput, "empty output")
+	}
+	return res, nil
+}
+
+// truncateTail keeps the last n bytes of s, where error output usually is.
+func truncateTail(s string, n int) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= n {
+		return s
+	}
+	return "..." + s[len(s)-n:]
+}
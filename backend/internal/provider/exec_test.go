@@ -0,0 +1,144 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestExecHelperProcess is the command the Exec tests run: the test binary
+// re-executed with -test.run and SWARMONE_EXEC_HELPER set to a behavior.
+func TestExecHelperProcess(t *testing.T) {
+	mode := os.Getenv("SWARMONE_EXEC_HELPER")
+	if mode == "" {
+		return
+	}
+	switch mode {
+	case "echo":
+		var in ExecInput
+		if err := json.NewDecoder(os.Stdin).Decode(&in); err != nil {
+			fmt.Fprintf(os.Stderr, "bad input: %v", err)
+			os.Exit(2)
+		}
+		last := in.Messages[len(in.Messages)-1]
+		json.NewEncoder(os.Stdout).Encode(ExecOutput{
+			Text:  fmt.Sprintf("%s/%s/%s/%d", in.Model, in.Messages[0].Content, last.Content, in.MaxTokens),
+			Usage: &Usage{InputTokens: 7, OutputTokens: 3},
+		})
+	case "report":
+		fmt.Print(`{"error":{"kind":"rate_limit","message":"busy","retryable":true}}`)
+	case "garbage":
+		fmt.Print("this is not JSON")
+	case "exit":
+		fmt.Fprint(os.Stderr, "rules file not found")
+		os.Exit(3)
+	case "hang":
+		os.WriteFile(os.Getenv("SWARMONE_EXEC_PIDFILE"), []byte(strconv.Itoa(os.Getpid())), 0o644)
+		time.Sleep(time.Minute)
+	}
+	os.Exit(0)
+}
+
+func helperExec(mode string, timeout time.Duration, env map[string]string) *Exec {
+	e := map[string]string{"SWARMONE_EXEC_HELPER": mode}
+	for k, v := range env {
+		e[k] = v
+	}
+	return &Exec{Model: "rules-v1", Command: []string{os.Args[0], "-test.run=^TestExecHelperProcess$"}, Env: e, Timeout: timeout}
+}
+
+func TestExec(t *testing.T) {
+	req := Request{
+		Messages:  []Message{{Role: RoleSystem, Content: "sys"}, {Role: RoleUser, Content: "ping"}},
+		MaxTokens: 32,
+	}
+	tests := []struct {
+		name        string
+		mode        string
+		wantText    string
+		wantKind    ErrorKind
+		wantMessage string
+	}{
+		{name: "JSON round trip", mode: "echo", wantText: "rules-v1/sys/ping/32"},
+		{name: "reported error keeps its kind", mode: "report", wantKind: KindRateLimit, wantMessage: "busy"},
+		{name: "stdout that is not JSON", mode: "garbage", wantKind: KindBadResponse, wantMessage: "stdout is not JSON"},
+		{name: "non-zero exit carries stderr", mode: "exit", wantKind: KindServer, wantMessage: "exited with 3: rules file not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := helperExec(tt.mode, 10*time.Second, nil).Generate(context.Background(), req)
+			if tt.wantKind == "" {
+				if err != nil {
+					t.Fatalf("Generate: %v", err)
+				}
+				if res.Text != tt.wantText || res.Usage.InputTokens != 7 || res.Usage.OutputTokens != 3 || res.FinishReason != "stop" {
+					t.Errorf("result = %+v, want text %q", res, tt.wantText)
+				}
+				return
+			}
+			pe := AsError(err)
+			if pe == nil || pe.Kind != tt.wantKind || !strings.Contains(pe.Message, tt.wantMessage) {
+				t.Fatalf("error = %v, want kind %s with %q", err, tt.wantKind, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestExecKillsTheChild(t *testing.T) {
+	tests := []struct {
+		name     string
+		cancel   bool // cancel the caller's context instead of hitting Timeout
+		wantKind ErrorKind
+	}{
+		{name: "caller cancels", cancel: true, wantKind: KindCanceled},
+		{name: "timeout", wantKind: KindTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pidFile := filepath.Join(t.TempDir(), "pid")
+			timeout := 500 * time.Millisecond
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				timeout = time.Minute
+				go func() {
+					// Cancel once the child is up.
+					for ctx.Err() == nil {
+						if _, err := os.Stat(pidFile); err == nil {
+							cancel()
+							return
+						}
+						time.Sleep(10 * time.Millisecond)
+					}
+				}()
+			}
+
+			start := time.Now()
+			_, err := helperExec("hang", timeout, map[string]string{"SWARMONE_EXEC_PIDFILE": pidFile}).Generate(ctx, Prompt("wait", 8))
+			if el := time.Since(start); el > 10*time.Second {
+				t.Errorf("Generate returned after %s", el)
+			}
+			if pe := AsError(err); pe == nil || pe.Kind != tt.wantKind {
+				t.Fatalf("error = %v, want kind %s", err, tt.wantKind)
+			}
+
+			raw, err := os.ReadFile(pidFile)
+			if err != nil {
+				t.Fatalf("child never started: %v", err)
+			}
+			pid, _ := strconv.Atoi(string(raw))
+			p, _ := os.FindProcess(pid)
+			if err := p.Signal(syscall.Signal(0)); err == nil {
+				p.Kill()
+				t.Errorf("child %d still running", pid)
+			}
+		})
+	}
+}
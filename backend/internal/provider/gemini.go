@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -20,6 +19,7 @@ type Gemini struct {
 	Model string
 	Key   string
 	HTTP  *http.Client
+	Retry RetryPolicy
 }
 
 func NewGemini(model, key string) Client {
@@ -36,95 +36,354 @@ func (g *Gemini) ensureHTTP() {
 			timeout = d
 		}
 	}
-	cl := &http.Client{}
-	cl.Timeout = timeout
-	g.HTTP = cl
+	g.HTTP = newHTTPClient("gemini", timeout)
 }
 
-func (g *Gemini) Generate(ctx context.Context, prompt string, maxTokens int) (string, string, error) {
-	if g.Key == "" {
-		return "", "", errors.New("gemini api key missing")
+func (g *Gemini) Generate(ctx context.Context, r Request) (Result, error) {
+	if g.Key == "" && !replaying() {
+		return Result{}, newError("gemini", KindAuth, "api key missing")
 	}
 	g.ensureHTTP()
 
-	type part struct {
-		Text string `json:"text,omitempty"`
+	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", g.Model, g.Key)
+	resp, attempts, err := g.Retry.do(ctx, g.HTTP, func() (*http.Request, error) {
+		return g.newRequest(ctx, url, g.body(r))
+	})
+	if err != nil {
+		return Result{Attempts: attempts}, transportError(ctx, "gemini", err)
 	}
-	type content struct {
-		Role  string `json:"role,omitempty"`
-		Parts []part `json:"parts,omitempty"`
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Result{Attempts: attempts}, httpError("gemini", resp.StatusCode, raw)
 	}
-	body := map[string]any{
-		"contents": []content{{Role: "user", Parts: []part{{Text: prompt}}}},
+
+	var jr map[string]any
+	if err := json.Unmarshal(raw, &jr); err != nil {
+		return Result{Attempts: attempts}, newError("gemini", KindBadResponse, "decode error: %v; body=%s", err, string(raw))
 	}
-	if maxTokens > 0 {
-		body["generationConfig"] = map[string]any{"maxOutputTokens": maxTokens}
+	res := Result{
+		Attempts:     attempts,
+		FinishReason: geminiFinishReason(jr),
+		RequestID:    asString(jr["responseId"]),
+		Usage:        geminiUsage(jr["usageMetadata"]),
 	}
 
-	b, _ := json.Marshal(body)
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", g.Model, g.Key)
+	// If blocked by safety, the API often returns promptFeedback.blockReason
+	if pf, ok := jr["promptFeedback"].(map[string]any); ok {
+		if br, ok := pf["blockReason"].(string); ok && br != "" {
+			return res, newError("gemini", KindSafety, "safety block: %s", br)
+		}
+	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
-	if err != nil {
-		return "", "", err
+	res.Text = strings.TrimSpace(geminiText(jr, "\n", true))
+	res.ToolCalls = geminiToolCalls(jr, 0)
+	if res.Text == "" && len(res.ToolCalls) > 0 {
+		return res, nil
 	}
-	req.Header.Set("Content-Type", "application/json")
+	if res.Text == "" && geminiSafetyFinish(res.FinishReason) {
+		return res, newError("gemini", KindSafety, "safety block: finish_reason=%s", res.FinishReason)
+	}
+	if res.Text == "" {
+		// Nothing usable and no explicit blockReason → let caller see a generic error
+		return res, newError("gemini", KindEmptyOutput, "empty output")
+	}
+	return res, nil
+}
 
-	resp, err := g.HTTP.Do(req)
+// Stream uses :streamGenerateContent?alt=sse; every SSE chunk is a partial
+// GenerateContentResponse whose candidate text is forwarded to onDelta.
+// The last chunk carries the finish reason and final usageMetadata.
+func (g *Gemini) Stream(ctx context.Context, r Request, onDelta func(string)) (Result, error) {
+	if g.Key == "" && !replaying() {
+		return Result{}, newError("gemini", KindAuth, "api key missing")
+	}
+	g.ensureHTTP()
+
+	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:streamGenerateContent?alt=sse&key=%s", g.Model, g.Key)
+	resp, attempts, err := g.Retry.do(ctx, g.HTTP, func() (*http.Request, error) {
+		req, err := g.newRequest(ctx, url, g.body(r))
+		if err == nil {
+			req.Header.Set("Accept", "text/event-stream")
+		}
+		return req, err
+	})
 	if err != nil {
-		return "", "", err
+		return Result{Attempts: attempts}, transportError(ctx, "gemini", err)
 	}
 	defer resp.Body.Close()
 
-	raw, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", "", fmt.Errorf("gemini http %d: %s", resp.StatusCode, string(raw))
+		raw, _ := io.ReadAll(resp.Body)
+		return Result{Attempts: attempts}, httpError("gemini", resp.StatusCode, raw)
 	}
 
-	var jr map[string]any
-	if err := json.Unmarshal(raw, &jr); err != nil {
-		return "", "", fmt.Errorf("gemini decode error: %v; body=%s", err, string(raw))
+	res := Result{Attempts: attempts}
+	var sb strings.Builder
+	err = readSSE(resp.Body, func(event, data string) error {
+		var jr map[string]any
+		if err := json.Unmarshal([]byte(data), &jr); err != nil {
+			return newError("gemini", KindBadResponse, "stream decode error: %v; data=%s", err, data)
+		}
+		if pf, ok := jr["promptFeedback"].(map[string]any); ok {
+			if br, ok := pf["blockReason"].(string); ok && br != "" {
+				return newError("gemini", KindSafety, "safety block: %s", br)
+			}
+		}
+		if fr := geminiFinishReason(jr); fr != "" {
+			res.FinishReason = fr
+		}
+		if id := asString(jr["responseId"]); id != "" {
+			res.RequestID = id
+		}
+		if um, ok := jr["usageMetadata"]; ok {
+			res.Usage = geminiUsage(um)
+		}
+		res.ToolCalls = append(res.ToolCalls, geminiToolCalls(jr, len(res.ToolCalls))...)
+		if d := geminiText(jr, "", false); d != "" {
+			sb.WriteString(d)
+			if onDelta != nil {
+				onDelta(d)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return res, transportError(ctx, "gemini", err)
 	}
 
-	// If blocked by safety, the API often returns promptFeedback.blockReason
-	if pf, ok := jr["promptFeedback"].(map[string]any); ok {
-		if br, ok := pf["blockReason"].(string); ok && br != "" {
-			return "", "", fmt.Errorf("gemini safety block: %s", br)
+	res.Text = strings.TrimSpace(sb.String())
+	if res.Text == "" && len(res.ToolCalls) > 0 {
+		return res, nil
+	}
+	if res.Text == "" && geminiSafetyFinish(res.FinishReason) {
+		return res, newError("gemini", KindSafety, "safety block: finish_reason=%s", res.FinishReason)
+	}
+	if res.Text == "" {
+		return res, newError("gemini", KindEmptyOutput, "empty output (finish_reason=%q)", res.FinishReason)
+	}
+	return res, nil
+}
+
+// body maps system messages to systemInstruction and the remaining turns to
+// contents, using Gemini's "model" role for assistant messages. Tool calls
+// become functionCall parts; consecutive tool results are sent together as
+// functionResponse parts of a single user turn.
+func (g *Gemini) body(r Request) map[string]any {
+	turns := r.Turns()
+	contents := make([]map[string]any, 0, len(turns))
+	var results []any
+	for _, m := range turns {
+		switch m.Role {
+		case RoleTool:
+			part := map[string]any{"functionResponse": map[string]any{
+				"name":     m.Name,
+				"response": toolOutputObject(m.Content),
+			}}
+			if results != nil {
+				results = append(results, part)
+				contents[len(contents)-1]["parts"] = results
+				continue
+			}
+			results = []any{part}
+			contents = append(contents, map[string]any{"role": "user", "parts": results})
+			continue
+		case RoleAssistant:
+			parts := []any{}
+			if m.Content != "" || len(m.ToolCalls) == 0 {
+				parts = append(parts, map[string]any{"text": m.Content})
+			}
+			for _, tc := range m.ToolCalls {
+				parts = append(parts, map[string]any{"functionCall": map[string]any{
+					"name": tc.Name,
+					"args": tc.argsObject(),
+				}})
+			}
+			contents = append(contents, map[string]any{"role": "model", "parts": parts})
+		default:
+			parts := []any{map[string]any{"text": m.Content}}
+			for _, a := range m.Attachments {
+				parts = append(parts, map[string]any{"inlineData": map[string]any{"mimeType": a.MIMEType, "data": a.base64()}})
+			}
+			contents = append(contents, map[string]any{"role": "user", "parts": parts})
+		}
+		results = nil
+	}
+	body := map[string]any{
+		"contents": contents,
+	}
+	if sys := r.System(); sys != "" {
+		body["systemInstruction"] = map[string]any{"parts": []any{map[string]any{"text": sys}}}
+	}
+	gen := map[string]any{}
+	if r.MaxTokens > 0 {
+		gen["maxOutputTokens"] = r.Reasoning.outputBudget(r.MaxTokens)
+	}
+	if r.Reasoning != nil {
+		gen["thinkingConfig"] = map[string]any{"thinkingBudget": r.Reasoning.budget()}
+	}
+	s := r.Sampling
+	if s.Temperature != nil {
+		gen["temperature"] = *s.Temperature
+	}
+	if s.TopP != nil {
+		gen["topP"] = *s.TopP
+	}
+	if s.TopK != nil {
+		gen["topK"] = *s.TopK
+	}
+	if len(s.Stop) > 0 {
+		gen["stopSequences"] = s.Stop
+	}
+	if s.Seed != nil {
+		gen["seed"] = *s.Seed
+	}
+	if s.PresencePenalty != nil {
+		gen["presencePenalty"] = *s.PresencePenalty
+	}
+	if s.FrequencyPenalty != nil {
+		gen["frequencyPenalty"] = *s.FrequencyPenalty
+	}
+	if r.Output != nil {
+		gen["responseMimeType"] = "application/json"
+		gen["responseSchema"] = geminiSchema(r.Output.Schema)
+	}
+	if len(gen) > 0 {
+		body["generationConfig"] = gen
+	}
+	if len(r.Tools) > 0 {
+		decls := make([]map[string]any, 0, len(r.Tools))
+		for _, t := range r.Tools {
+			d := map[string]any{"name": t.Name, "description": t.Description}
+			// Gemini rejects an object schema without properties, so a
+			// parameterless tool simply omits the field.
+			if len(t.Parameters) > 0 {
+				d["parameters"] = t.Parameters
+			}
+			decls = append(decls, d)
 		}
+		body["tools"] = []any{map[string]any{"functionDeclarations": decls}}
 	}
+	return body
+}
+
+// geminiToolCalls extracts functionCall parts from the first candidate.
+// Gemini usually omits call IDs, so one is synthesized from the name and
+// position (offset by n when accumulating across stream chunks).
+func geminiToolCalls(jr map[string]any, n int) []ToolCall {
+	cands, _ := jr["candidates"].([]any)
+	if len(cands) == 0 {
+		return nil
+	}
+	c0, _ := cands[0].(map[string]any)
+	content, _ := c0["content"].(map[string]any)
+	parts, _ := content["parts"].([]any)
+	var calls []ToolCall
+	for _, p := range parts {
+		pm, _ := p.(map[string]any)
+		fc, ok := pm["functionCall"].(map[string]any)
+		if !ok {
+			continue
+		}
+		name := asString(fc["name"])
+		args, _ := json.Marshal(fc["args"])
+		id := asString(fc["id"])
+		if id == "" {
+			id = syntheticCallID(name, n+len(calls))
+		}
+		calls = append(calls, ToolCall{ID: id, Name: name, Arguments: args})
+	}
+	return calls
+}
 
+func (g *Gemini) newRequest(ctx context.Context, url string, body map[string]any) (*http.Request, error) {
+	b, _ := json.Marshal(body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// geminiText concatenates candidates[].content.parts[].text. With trim set,
+// parts are trimmed and joined by sep (full responses); otherwise they are
+// kept verbatim so streamed chunks keep their whitespace.
+func geminiText(jr map[string]any, sep string, trim bool) string {
 	var out strings.Builder
-	if cands, ok := jr["candidates"].([]any); ok {
-		for _, c := range cands {
-			cm, ok := c.(map[string]any)
+	cands, _ := jr["candidates"].([]any)
+	for _, c := range cands {
+		cm, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		cont, _ := cm["content"].(map[string]any)
+		if cont == nil {
+			continue
+		}
+		parts, _ := cont["parts"].([]any)
+		for _, p := range parts {
+			pm, ok := p.(map[string]any)
+			if !ok {
+				continue
+			}
+			s, ok := pm["text"].(string)
 			if !ok {
 				continue
 			}
-			cont, _ := cm["content"].(map[string]any)
-			if cont == nil {
+			if trim {
+				s = strings.TrimSpace(s)
+			}
+			if s == "" {
 				continue
 			}
-			if parts, ok := cont["parts"].([]any); ok {
-				for _, p := range parts {
-					if pm, ok := p.(map[string]any); ok {
-						if s, ok := pm["text"].(string); ok {
-							if ts := strings.TrimSpace(s); ts != "" {
-								if out.Len() > 0 {
-									out.WriteByte('\n')
-								}
-								out.WriteString(ts)
-							}
-						}
-					}
-				}
+			if out.Len() > 0 {
+				out.WriteString(sep)
 			}
+			out.WriteString(s)
 		}
 	}
+	return out.String()
+}
 
-	txt := strings.TrimSpace(out.String())
-	if txt == "" {
-		// Nothing usable and no explicit blockReason → let caller see a generic error
-		return "", "", errors.New("gemini empty output")
+// geminiFinishReason returns the first candidate's finishReason.
+func geminiFinishReason(jr map[string]any) string {
+	cands, _ := jr["candidates"].([]any)
+	for _, c := range cands {
+		if cm, ok := c.(map[string]any); ok {
+			if fr, ok := cm["finishReason"].(string); ok && fr != "" {
+				return fr
+			}
+		}
+	}
+	return ""
+}
+
+func geminiUsage(v any) Usage {
+	var u struct {
+		PromptTokenCount        int `json:"promptTokenCount"`
+		CandidatesTokenCount    int `json:"candidatesTokenCount"`
+		CachedContentTokenCount int `json:"cachedContentTokenCount"`
+		ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+	}
+	decodeInto(v, &u)
+	// candidatesTokenCount excludes thinking; fold it in to match Usage.
+	return Usage{
+		InputTokens:     u.PromptTokenCount,
+		OutputTokens:    u.CandidatesTokenCount + u.ThoughtsTokenCount,
+		CachedTokens:    u.CachedContentTokenCount,
+		ReasoningTokens: u.ThoughtsTokenCount,
+	}
+}
+
+func (g *Gemini) SetRetryPolicy(p RetryPolicy) { g.Retry = p }
+
+// geminiSafetyFinish reports finish reasons that mean the output was withheld.
+func geminiSafetyFinish(fr string) bool {
+	switch fr {
+	case "SAFETY", "PROHIBITED_CONTENT", "BLOCKLIST", "SPII", "RECITATION":
+		return true
 	}
-	return txt, "", nil
+	return false
 }
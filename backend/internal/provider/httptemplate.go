@@ -0,0 +1,374 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPTemplate describes an in-house endpoint with a custom JSON shape.
+//
+// Body is a JSON request body with {{placeholders}}: prompt (system and turns
+// flattened to text), system, instruction (last user message), messages
+// (array of {role, content}), model and max_tokens. A placeholder that is a
+// whole JSON string ("{{prompt}}") is replaced by the JSON value; inside a
+// longer string it is spliced in escaped. URL and header values may use
+// {{api_key}} and {{model}}.
+//
+// The *Path fields select values from the response with a JSONPath-style
+// expression: "$.choices[0].message.content", "data.outputs[0]['text']".
+type HTTPTemplate struct {
+	URL    string          `json:"url"`
+	Method string          `json:"method,omitempty"` // default POST
+	Body   json.RawMessage `json:"body"`             // JSON object, or a string holding the template text
+
+	ResponsePath     string `json:"response_path"`
+	FinishReasonPath string `json:"finish_reason_path,omitempty"`
+	InputTokensPath  string `json:"input_tokens_path,omitempty"`
+	OutputTokensPath string `json:"output_tokens_path,omitempty"`
+	ErrorPath        string `json:"error_path,omitempty"` // a non-empty string or object here fails the call even on 2xx
+}
+
+// TemplateHTTP is the client for an HTTPTemplate.
+type TemplateHTTP struct {
+	Model    string
+	Key      string
+	Headers  map[string]string
+	Template HTTPTemplate
+	Timeout  time.Duration
+	HTTP     *http.Client
+	Retry    RetryPolicy
+}
+
+func NewTemplateHTTP(model, key string, headers map[string]string, t HTTPTemplate, timeout time.Duration) (Client, error) {
+	if strings.TrimSpace(t.URL) == "" {
+		return nil, fmt.Errorf("http template: url missing")
+	}
+	if strings.TrimSpace(t.ResponsePath) == "" {
+		return nil, fmt.Errorf("http template: response_path missing")
+	}
+	for _, p := range []string{t.ResponsePath, t.FinishReasonPath, t.InputTokensPath, t.OutputTokensPath, t.ErrorPath} {
+		if _, err := parsePath(p); err != nil {
+			return nil, fmt.Errorf("http template: %w", err)
+		}
+	}
+	if _, err := t.bodyText(); err != nil {
+		return nil, err
+	}
+	return &TemplateHTTP{Model: model, Key: key, Headers: headers, Template: t, Timeout: timeout}, nil
+}
+
+func (c *TemplateHTTP) ensureHTTP() {
+	if c.HTTP != nil {
+		return
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	c.HTTP = newHTTPClient("http", timeout)
+}
+
+func (c *TemplateHTTP) Generate(ctx context.Context, r Request) (Result, error) {
+	c.ensureHTTP()
+	body, err := c.render(r)
+	if err != nil {
+		return Result{}, err
+	}
+
+	resp, attempts, err := c.Retry.do(ctx, c.HTTP, func() (*http.Request, error) {
+		return c.newRequest(ctx, body)
+	})
+	if err != nil {
+		return Result{Attempts: attempts}, transportError(ctx, "http", err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Result{Attempts: attempts}, httpError("http", resp.StatusCode, raw)
+	}
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return Result{Attempts: attempts}, newError("http", KindBadResponse, "decode error: %v; body=%s", err, string(raw))
+	}
+	t := c.Template
+	if e := templateError(lookupPath(doc, t.ErrorPath)); e != nil {
+		return Result{Attempts: attempts}, e
+	}
+	res := Result{
+		Attempts:     attempts,
+		RequestID:    resp.Header.Get("x-request-id"),
+		Text:         strings.TrimSpace(pathText(lookupPath(doc, t.ResponsePath))),
+		FinishReason: asString(lookupPath(doc, t.FinishReasonPath)),
+		Usage: Usage{
+			InputTokens:  pathInt(lookupPath(doc, t.InputTokensPath)),
+			OutputTokens: pathInt(lookupPath(doc, t.OutputTokensPath)),
+		},
+	}
+	if res.Text == "" {
+		return res, newError("http", KindEmptyOutput, "empty output at %s", t.ResponsePath)
+	}
+	return res, nil
+}
+
+// templateError returns the error reported at ErrorPath, or nil. Only a
+// non-empty string or object counts: gateways often send "error": false, 0
+// or null on success. An object's message (and a numeric status or code of
+// 400 and up) are classified like an HTTP error body; without a status the
+// error is an invalid request, so it is neither retried nor held against the
+// breaker.
+func templateError(v any) *Error {
+	status := 0
+	switch t := v.(type) {
+	case string:
+		if strings.TrimSpace(t) == "" {
+			return nil
+		}
+	case map[string]any:
+		if len(t) == 0 {
+			return nil
+		}
+		obj := make(map[string]any, len(t))
+		for k, x := range t {
+			obj[k] = x
+		}
+		for _, k := range []string{"status", "code"} {
+			if n, ok := t[k].(float64); ok {
+				if status == 0 && n >= 400 && n < 600 {
+					status = int(n)
+				}
+				delete(obj, k) // parseErrorBody expects string codes
+			}
+		}
+		v = obj
+	default:
+		return nil
+	}
+	body, _ := json.Marshal(map[string]any{"error": v})
+	if status != 0 {
+		return httpError("http", status, body)
+	}
+	e := httpError("http", http.StatusBadRequest, body)
+	e.Status = 0
+	return e
+}
+
+func (c *TemplateHTTP) newRequest(ctx context.Context, body []byte) (*http.Request, error) {
+	method := strings.ToUpper(firstNonEmpty(c.Template.Method, http.MethodPost))
+	req, err := http.NewRequestWithContext(ctx, method, c.expand(c.Template.URL), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.Headers {
+		req.Header.Set(k, c.expand(v))
+	}
+	return req, nil
+}
+
+// expand fills {{api_key}} and {{model}} in URL and header values.
+func (c *TemplateHTTP) expand(s string) string {
+	return strings.NewReplacer("{{api_key}}", c.Key, "{{model}}", c.Model).Replace(s)
+}
+
+var placeholderRe = regexp.MustCompile(`"\{\{\s*(\w+)\s*\}\}"|\{\{\s*(\w+)\s*\}\}`)
+
+func (t HTTPTemplate) bodyText() (string, error) {
+	if len(t.Body) == 0 {
+		return "", fmt.Errorf("http template: body missing")
+	}
+	var s string
+	if json.Unmarshal(t.Body, &s) == nil {
+		return s, nil
+	}
+	return string(t.Body), nil
+}
+
+// render fills the body template for r and checks the result is valid JSON.
+func (c *TemplateHTTP) render(r Request) ([]byte, error) {
+	tmpl, err := c.Template.bodyText()
+	if err != nil {
+		return nil, err
+	}
+	vals := c.values(r)
+	var unknown string
+	out := placeholderRe.ReplaceAllStringFunc(tmpl, func(m string) string {
+		sm := placeholderRe.FindStringSubmatch(m)
+		name, quoted := sm[1], true
+		if name == "" {
+			name, quoted = sm[2], false
+		}
+		v, ok := vals[name]
+		if !ok {
+			unknown = name
+			return m
+		}
+		b, _ := json.Marshal(v)
+		if !quoted {
+			// Inside a longer string: splice the escaped text without quotes.
+			if s, isStr := v.(string); isStr {
+				b, _ = json.Marshal(s)
+				b = b[1 : len(b)-1]
+			}
+		}
+		return string(b)
+	})
+	if unknown != "" {
+		return nil, newError("http", KindInvalidRequest, "body template: unknown placeholder {{%s}}", unknown)
+	}
+	if !json.Valid([]byte(out)) {
+		return nil, newError("http", KindInvalidRequest, "body template does not render to valid JSON: %s", out)
+	}
+	return []byte(out), nil
+}
+
+func (c *TemplateHTTP) values(r Request) map[string]any {
+	type msg struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	var msgs []msg
+	var lines []string
+	instruction := ""
+	if sys := r.System(); sys != "" {
+		msgs = append(msgs, msg{Role: RoleSystem, Content: sys})
+		lines = append(lines, sys)
+	}
+	for _, m := range r.Turns() {
+		msgs = append(msgs, msg{Role: m.Role, Content: m.Content})
+		lines = append(lines, m.Content)
+		if m.Role == RoleUser {
+			instruction = m.Content
+		}
+	}
+	return map[string]any{
+		"prompt":      strings.Join(lines, "\n\n"),
+		"system":      r.System(),
+		"instruction": instruction,
+		"messages":    msgs,
+		"model":       c.Model,
+		"max_tokens":  r.MaxTokens,
+	}
+}
+
+func (c *TemplateHTTP) SetRetryPolicy(p RetryPolicy) { c.Retry = p }
+
+// pathStep is one field name or array index of a parsed path.
+type pathStep struct {
+	key   string
+	index int
+	isIdx bool
+}
+
+// parsePath parses a JSONPath-style expression: an optional "$", then
+// .field, [n] and ['field'] / ["field"] steps. Negative indexes count from
+// the end. The empty path selects nothing.
+func parsePath(p string) ([]pathStep, error) {
+	p = strings.TrimSpace(p)
+	p = strings.TrimPrefix(p, "$")
+	var steps []pathStep
+	for i := 0; i < len(p); {
+		switch p[i] {
+		case '.':
+			i++
+		case '[':
+			end := strings.IndexByte(p[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("path %q: unclosed [", p)
+			}
+			inner := strings.TrimSpace(p[i+1 : i+end])
+			i += end + 1
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				steps = append(steps, pathStep{key: inner[1 : len(inner)-1]})
+				continue
+			}
+			n, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("path %q: bad index [%s]", p, inner)
+			}
+			steps = append(steps, pathStep{index: n, isIdx: true})
+		default:
+			j := i
+			for j < len(p) && p[j] != '.' && p[j] != '[' {
+				j++
+			}
+			steps = append(steps, pathStep{key: p[i:j]})
+			i = j
+		}
+	}
+	return steps, nil
+}
+
+// lookupPath returns the value at path in doc, or nil.
+func lookupPath(doc any, path string) any {
+	if strings.TrimSpace(path) == "" {
+		return nil
+	}
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil
+	}
+	cur := doc
+	for _, s := range steps {
+		switch v := cur.(type) {
+		case map[string]any:
+			if s.isIdx {
+				return nil
+			}
+			cur = v[s.key]
+		case []any:
+			if !s.isIdx {
+				return nil
+			}
+			i := s.index
+			if i < 0 {
+				i += len(v)
+			}
+			if i < 0 || i >= len(v) {
+				return nil
+			}
+			cur = v[i]
+		default:
+			return nil
+		}
+	}
+	return cur
+}
+
+// pathText renders a selected value as answer text: strings as is, arrays of
+// strings joined, anything else as JSON.
+func pathText(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case []any:
+		parts := make([]string, 0, len(t))
+		for _, e := range t {
+			parts = append(parts, pathText(e))
+		}
+		return strings.Join(parts, "")
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func pathInt(v any) int {
+	switch t := v.(type) {
+	case float64:
+		return int(t)
+	case string:
+		n, _ := strconv.Atoi(t)
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,182 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []pathStep
+		wantErr bool
+	}{
+		{path: "", want: nil},
+		{path: "$", want: nil},
+		{path: "text", want: []pathStep{{key: "text"}}},
+		{path: "$.choices[0].message.content", want: []pathStep{{key: "choices"}, {index: 0, isIdx: true}, {key: "message"}, {key: "content"}}},
+		{path: "data.outputs[-1]['text']", want: []pathStep{{key: "data"}, {key: "outputs"}, {index: -1, isIdx: true}, {key: "text"}}},
+		{path: `$["a.b"][ 2 ]`, want: []pathStep{{key: "a.b"}, {index: 2, isIdx: true}}},
+		{path: "a[0", wantErr: true},
+		{path: "a[x]", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parsePath(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parsePath(%q) = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestLookupPath(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(`{
+  "choices": [{"message": {"content": "hi"}}, {"message": {"content": "last"}}],
+  "usage": {"in": 12},
+  "a.b": true
+}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want any
+	}{
+		{"$.choices[0].message.content", "hi"},
+		{"choices[-1].message.content", "last"},
+		{"usage.in", 12.0},
+		{`$['a.b']`, true},
+		{"", nil},
+		{"choices[5]", nil},
+		{"choices[-3]", nil},
+		{"choices.message", nil}, // key step on an array
+		{"usage[0]", nil},        // index step on an object
+		{"usage.in.deeper", nil}, // step into a scalar
+		{"missing.field", nil},
+		{"a[", nil}, // unparsable
+	}
+	for _, tt := range tests {
+		if got := lookupPath(doc, tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lookupPath(%q) = %#v, want %#v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestTemplateRender(t *testing.T) {
+	req := Request{
+		Messages: []Message{
+			{Role: RoleSystem, Content: "Be brief."},
+			{Role: RoleUser, Content: `Say "hi"`},
+		},
+		MaxTokens: 64,
+	}
+	tests := []struct {
+		name    string
+		body    string
+		want    string // compared as JSON
+		wantErr string
+	}{
+		{
+			name: "whole-string placeholders become JSON values",
+			body: `{"model":"{{model}}","max":"{{max_tokens}}","messages":"{{messages}}"}`,
+			want: `{"model":"m1","max":64,"messages":[{"role":"system","content":"Be brief."},{"role":"user","content":"Say \"hi\""}]}`,
+		},
+		{
+			name: "placeholders inside a string are spliced escaped",
+			body: `{"input":"Q: {{instruction}} (sys: {{ system }})"}`,
+			want: `{"input":"Q: Say \"hi\" (sys: Be brief.)"}`,
+		},
+		{
+			name: "prompt flattens system and turns",
+			body: `{"prompt":"{{prompt}}"}`,
+			want: `{"prompt":"Be brief.\n\nSay \"hi\""}`,
+		},
+		{
+			name: "template given as a JSON string",
+			body: `"{\"q\": \"{{instruction}}\", \"n\": {{max_tokens}}}"`,
+			want: `{"q":"Say \"hi\"","n":64}`,
+		},
+		{name: "unknown placeholder", body: `{"x":"{{nope}}"}`, wantErr: "unknown placeholder {{nope}}"},
+		{name: "invalid JSON after rendering", body: `"{\"q\": {{max_tokens}}"`, wantErr: "valid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &TemplateHTTP{Model: "m1", Template: HTTPTemplate{Body: json.RawMessage(tt.body)}}
+			got, err := c.render(req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("render error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("render: %v", err)
+			}
+			var g, w any
+			_ = json.Unmarshal(got, &g)
+			_ = json.Unmarshal([]byte(tt.want), &w)
+			if !reflect.DeepEqual(g, w) {
+				t.Errorf("render = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateErrorPath(t *testing.T) {
+	tests := []struct {
+		name          string
+		errorValue    string // JSON of the "error" field
+		wantKind      ErrorKind
+		wantMessage   string
+		wantStatus    int
+		wantRetryable bool
+	}{
+		{name: "null is success", errorValue: `null`},
+		{name: "false is success", errorValue: `false`},
+		{name: "zero is success", errorValue: `0`},
+		{name: "empty string is success", errorValue: `"  "`},
+		{name: "empty object is success", errorValue: `{}`},
+		{name: "string is an invalid request", errorValue: `"model not loaded"`, wantKind: KindInvalidRequest, wantMessage: "model not loaded"},
+		{name: "object message is used", errorValue: `{"message":"bad prompt","type":"validation"}`, wantKind: KindInvalidRequest, wantMessage: "bad prompt"},
+		{name: "object status classifies", errorValue: `{"message":"upstream busy","status":503}`, wantKind: KindServer, wantMessage: "upstream busy", wantStatus: 503, wantRetryable: true},
+		{name: "numeric code classifies", errorValue: `{"message":"slow down","code":429}`, wantKind: KindRateLimit, wantMessage: "slow down", wantStatus: 429, wantRetryable: true},
+		{name: "context length is recognized", errorValue: `"prompt is too long for this model"`, wantKind: KindContextLength, wantMessage: "prompt is too long for this model"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"output":"hello","error":%s}`, tt.errorValue)
+			}))
+			defer srv.Close()
+			cl, err := NewTemplateHTTP("m", "", nil, HTTPTemplate{
+				URL: srv.URL, Body: json.RawMessage(`{"q":"{{instruction}}"}`),
+				ResponsePath: "output", ErrorPath: "error",
+			}, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := cl.(*TemplateHTTP)
+			c.Retry = RetryPolicy{MaxAttempts: 1}
+			res, err := c.Generate(context.Background(), Prompt("hi", 16))
+			if tt.wantKind == "" {
+				if err != nil || res.Text != "hello" {
+					t.Fatalf("Generate = %q, %v; want hello", res.Text, err)
+				}
+				return
+			}
+			pe := AsError(err)
+			if pe == nil || pe.Kind != tt.wantKind || pe.Message != tt.wantMessage || pe.Status != tt.wantStatus || pe.Retryable != tt.wantRetryable {
+				t.Fatalf("Generate error = %+v, want kind %s message %q status %d retryable %v", pe, tt.wantKind, tt.wantMessage, tt.wantStatus, tt.wantRetryable)
+			}
+		})
+	}
+}
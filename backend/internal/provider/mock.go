@@ -0,0 +1,260 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mock is a scripted client for tests and demos; it never touches the network.
+// Responses come from a JSON fixture file (see MockFixture). The first rule whose
+// match fits the request answers it; rules with a sequence answer successive calls
+// with successive entries (the last one repeats). Fixtures are re-read when the
+// file changes, and sequence positions are shared by all clients using the file.
+type Mock struct {
+	Model   string
+	Fixture string // path to the fixture JSON
+}
+
+func NewMock(model, fixture string) Client {
+	return &Mock{Model: model, Fixture: fixture}
+}
+
+// MockFixture is the fixture file layout.
+type MockFixture struct {
+	Rules   []MockRule    `json:"rules"`
+	Default *MockResponse `json:"default,omitempty"` // used when no rule matches
+}
+
+// MockRule pairs a match with one response or a sequence of responses.
+type MockRule struct {
+	Match    MockMatch      `json:"match"`
+	Respond  *MockResponse  `json:"respond,omitempty"`
+	Sequence []MockResponse `json:"sequence,omitempty"`
+}
+
+// MockMatch conditions are ANDed; empty fields match anything.
+// Prompt text is the concatenation of all non-system messages.
+type MockMatch struct {
+	Model    string   `json:"model,omitempty"`    // exact model name
+	Contains []string `json:"contains,omitempty"` // substrings that must all occur in the prompt
+	Regex    string   `json:"regex,omitempty"`    // Go regexp matched against the prompt
+	System   string   `json:"system,omitempty"`   // substring of the system prompt (e.g. to target the judge)
+}
+
+// MockResponse is one scripted answer. JSON, when set, is sent verbatim as
+// the text (handy for judge output). ToolCalls asks the caller to run tools;
+// their results show up in the next prompt, so a rule matching the tool output
+// can script the follow-up answer. Error turns the call into a failure.
+type MockResponse struct {
+	Text         string          `json:"text,omitempty"`
+	JSON         json.RawMessage `json:"json,omitempty"`
+	ToolCalls    []ToolCall      `json:"tool_calls,omitempty"`
+	Latency      string          `json:"latency,omitempty"` // Go duration, e.g. "250ms"
+	FinishReason string          `json:"finish_reason,omitempty"`
+	Usage        *Usage          `json:"usage,omitempty"` // default: the local token estimate (see EstimateTokens)
+	Error        *MockError      `json:"error,omitempty"`
+}
+
+// MockError describes a scripted failure.
+type MockError struct {
+	Kind      ErrorKind `json:"kind"`
+	Status    int       `json:"status,omitempty"`
+	Code      string    `json:"code,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Retryable bool      `json:"retryable,omitempty"`
+}
+
+type mockState struct {
+	modTime time.Time
+	fx      MockFixture
+	res     []*regexp.Regexp
+	calls   []int // per rule
+}
+
+var mockFixtures = struct {
+	sync.Mutex
+	m map[string]*mockState
+}{m: map[string]*mockState{}}
+
+func (m *Mock) Generate(ctx context.Context, r Request) (Result, error) {
+	return m.Stream(ctx, r, nil)
+}
+
+// Stream replays the scripted text word by word, spreading the latency over the chunks.
+func (m *Mock) Stream(ctx context.Context, r Request, onDelta func(string)) (Result, error) {
+	resp, err := m.pick(r)
+	if err != nil {
+		return Result{}, err
+	}
+
+	text := resp.Text
+	if len(resp.JSON) > 0 {
+		text = string(resp.JSON)
+	}
+	chunks := []string{text}
+	if onDelta != nil {
+		chunks = splitKeep(text)
+	}
+	latency := parseDur(resp.Latency)
+	step := latency / time.Duration(max(len(chunks), 1))
+
+	res := Result{Attempts: 1, FinishReason: firstNonEmpty(resp.FinishReason, "stop"), RequestID: "mock-" + m.Model}
+	if resp.Usage != nil {
+		res.Usage = *resp.Usage
+	} else {
+		res.Usage = Usage{InputTokens: EstimateInputTokens("mock", r), OutputTokens: CountTokens("mock", text)}
+	}
+
+	var sb strings.Builder
+	for _, c := range chunks {
+		if step > 0 {
+			t := time.NewTimer(step)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return res, transportError(ctx, "mock", ctx.Err())
+			case <-t.C:
+			}
+		}
+		sb.WriteString(c)
+		if onDelta != nil && c != "" {
+			onDelta(c)
+		}
+	}
+
+	if e := resp.Error; e != nil {
+		return res, &Error{
+			Provider:  "mock",
+			Kind:      e.Kind,
+			Status:    e.Status,
+			Code:      e.Code,
+			Message:   firstNonEmpty(e.Message, "scripted "+string(e.Kind)+" error"),
+			Retryable: e.Retryable,
+		}
+	}
+	for i, tc := range resp.ToolCalls {
+		if tc.ID == "" {
+			tc.ID = syntheticCallID(tc.Name, i)
+		}
+		tc.Arguments = tc.args()
+		res.ToolCalls = append(res.ToolCalls, tc)
+	}
+	res.Text = strings.TrimSpace(sb.String())
+	if res.Text == "" && len(res.ToolCalls) == 0 {
+		return res, newError("mock", KindEmptyOutput, "empty output")
+	}
+	return res, nil
+}
+
+// pick loads the fixture (if changed) and returns the response for r.
+func (m *Mock) pick(r Request) (MockResponse, error) {
+	if strings.TrimSpace(m.Fixture) == "" {
+		return MockResponse{}, newError("mock", KindInvalidRequest, "fixture path missing")
+	}
+	st, err := os.Stat(m.Fixture)
+	if err != nil {
+		return MockResponse{}, newError("mock", KindInvalidRequest, "fixture: %v", err)
+	}
+
+	mockFixtures.Lock()
+	defer mockFixtures.Unlock()
+	s := mockFixtures.m[m.Fixture]
+	if s == nil || !s.modTime.Equal(st.ModTime()) {
+		s, err = loadMockFixture(m.Fixture, st.ModTime())
+		if err != nil {
+			return MockResponse{}, err
+		}
+		mockFixtures.m[m.Fixture] = s
+	}
+
+	var prompt []string
+	for _, msg := range r.Turns() {
+		prompt = append(prompt, msg.Content)
+	}
+	text := strings.Join(prompt, "\n")
+	system := r.System()
+
+	for i, rule := range s.fx.Rules {
+		mt := rule.Match
+		if mt.Model != "" && mt.Model != m.Model {
+			continue
+		}
+		if mt.System != "" && !strings.Contains(system, mt.System) {
+			continue
+		}
+		if s.res[i] != nil && !s.res[i].MatchString(text) {
+			continue
+		}
+		ok := true
+		for _, c := range mt.Contains {
+			if !strings.Contains(text, c) {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		if len(rule.Sequence) > 0 {
+			n := s.calls[i]
+			s.calls[i]++
+			return rule.Sequence[min(n, len(rule.Sequence)-1)], nil
+		}
+		if rule.Respond != nil {
+			return *rule.Respond, nil
+		}
+	}
+	if s.fx.Default != nil {
+		return *s.fx.Default, nil
+	}
+	return MockResponse{}, newError("mock", KindInvalidRequest, "no fixture rule matched (model=%s)", m.Model)
+}
+
+func loadMockFixture(path string, modTime time.Time) (*mockState, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, newError("mock", KindInvalidRequest, "fixture: %v", err)
+	}
+	s := &mockState{modTime: modTime}
+	if err := json.Unmarshal(raw, &s.fx); err != nil {
+		return nil, newError("mock", KindInvalidRequest, "fixture %s: %v", path, err)
+	}
+	s.res = make([]*regexp.Regexp, len(s.fx.Rules))
+	s.calls = make([]int, len(s.fx.Rules))
+	for i, rule := range s.fx.Rules {
+		if rule.Match.Regex == "" {
+			continue
+		}
+		re, err := regexp.Compile(rule.Match.Regex)
+		if err != nil {
+			return nil, newError("mock", KindInvalidRequest, "fixture %s rule %d: %v", path, i, err)
+		}
+		s.res[i] = re
+	}
+	return s, nil
+}
+
+// splitKeep splits s into words, keeping the separating whitespace attached.
+func splitKeep(s string) []string {
+	var out []string
+	start := 0
+	for i := 1; i < len(s); i++ {
+		if s[i] == ' ' && s[i-1] != ' ' {
+			out = append(out, s[start:i])
+			start = i
+		}
+	}
+	return append(out, s[start:])
+}
+
+func parseDur(s string) time.Duration {
+	if d, err := time.ParseDuration(strings.TrimSpace(s)); err == nil {
+		return d
+	}
+	return 0
+}
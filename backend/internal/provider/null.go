@@ -1,13 +1,10 @@
 package provider
 
-import (
-    "context"
-    "errors"
-)
+import "context"
 
 // Null client returns error; used for unknown providers.
 type Null struct{}
 
-func (*Null) Generate(ctx context.Context, instruction string, maxTokens int) (string, string, error) {
-    return "", "", errors.New("unknown provider")
+func (*Null) Generate(ctx context.Context, req Request) (Result, error) {
+	return Result{}, &Error{Kind: KindInvalidRequest, Message: "unknown provider"}
 }
@@ -0,0 +1,256 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ollama client for the native chat API.
+// POST {BaseURL}/api/chat; BaseURL defaults to $OLLAMA_HOST or http://localhost:11434.
+// Options are passed through as Ollama "options" (num_ctx, temperature, ...);
+// MaxTokens maps to options.num_predict. KeepAlive accepts a duration ("10m")
+// or seconds ("-1" keeps the model loaded, "0" unloads it right away).
+type Ollama struct {
+	Model     string
+	BaseURL   string
+	Options   map[string]any
+	KeepAlive string
+	HTTP      *http.Client
+	Retry     RetryPolicy
+}
+
+func NewOllama(model, baseURL string, options map[string]any, keepAlive string) Client {
+	return &Ollama{Model: model, BaseURL: baseURL, Options: options, KeepAlive: keepAlive}
+}
+
+func (o *Ollama) ensureHTTP() {
+	if o.HTTP != nil {
+		return
+	}
+	// Generous default: the first call may include loading the model into memory.
+	timeout := 120 * time.Second
+	if t := os.Getenv("OLLAMA_HTTP_TIMEOUT"); t != "" {
+		if d, err := time.ParseDuration(t); err == nil {
+			timeout = d
+		}
+	}
+	o.HTTP = newHTTPClient("ollama", timeout)
+}
+
+func (o *Ollama) baseURL() string {
+	base := firstNonEmpty(o.BaseURL, os.Getenv("OLLAMA_HOST"), "http://localhost:11434")
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+	return strings.TrimRight(base, "/")
+}
+
+// ollamaChunk is both the non-streaming response and one NDJSON stream line.
+type ollamaChunk struct {
+	Message struct {
+		Content   string           `json:"content"`
+		ToolCalls []ollamaToolCall `json:"tool_calls"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error"`
+}
+
+func (c ollamaChunk) usage() Usage {
+	return Usage{InputTokens: c.PromptEvalCount, OutputTokens: c.EvalCount}
+}
+
+// ollamaToolCall carries arguments as an object and has no call ID.
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+func ollamaToolCalls(in []ollamaToolCall, n int) []ToolCall {
+	out := make([]ToolCall, 0, len(in))
+	for _, tc := range in {
+		call := ToolCall{ID: syntheticCallID(tc.Function.Name, n+len(out)), Name: tc.Function.Name, Arguments: tc.Function.Arguments}
+		call.Arguments = call.args()
+		out = append(out, call)
+	}
+	return out
+}
+
+func (o *Ollama) Generate(ctx context.Context, r Request) (Result, error) {
+	o.ensureHTTP()
+
+	resp, attempts, err := o.Retry.do(ctx, o.HTTP, func() (*http.Request, error) {
+		return o.newRequest(ctx, o.payload(r, false))
+	})
+	if err != nil {
+		return Result{Attempts: attempts}, transportError(ctx, "ollama", err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Result{Attempts: attempts}, httpError("ollama", resp.StatusCode, raw)
+	}
+
+	var jr ollamaChunk
+	if err := json.Unmarshal(raw, &jr); err != nil {
+		return Result{Attempts: attempts}, newError("ollama", KindBadResponse, "decode error: %v; body=%s", err, string(raw))
+	}
+	if jr.Error != "" {
+		return Result{Attempts: attempts}, streamError("ollama", "", jr.Error)
+	}
+	res := Result{
+		Attempts:     attempts,
+		Text:         strings.TrimSpace(jr.Message.Content),
+		FinishReason: jr.DoneReason,
+		Usage:        jr.usage(),
+		ToolCalls:    ollamaToolCalls(jr.Message.ToolCalls, 0),
+	}
+	if res.Text == "" && len(res.ToolCalls) == 0 {
+		return res, newError("ollama", KindEmptyOutput, "empty output (done_reason=%q)", jr.DoneReason)
+	}
+	return res, nil
+}
+
+// Stream reads Ollama's newline-delimited JSON stream; the final line
+// (done: true) carries the done reason and token counts.
+func (o *Ollama) Stream(ctx context.Context, r Request, onDelta func(string)) (Result, error) {
+	o.ensureHTTP()
+
+	resp, attempts, err := o.Retry.do(ctx, o.HTTP, func() (*http.Request, error) {
+		return o.newRequest(ctx, o.payload(r, true))
+	})
+	if err != nil {
+		return Result{Attempts: attempts}, transportError(ctx, "ollama", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		raw, _ := io.ReadAll(resp.Body)
+		return Result{Attempts: attempts}, httpError("ollama", resp.StatusCode, raw)
+	}
+
+	res := Result{Attempts: attempts}
+	var sb strings.Builder
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var ch ollamaChunk
+		if err := json.Unmarshal(line, &ch); err != nil {
+			return res, newError("ollama", KindBadResponse, "stream decode error: %v; data=%s", err, string(line))
+		}
+		if ch.Error != "" {
+			return res, streamError("ollama", "", ch.Error)
+		}
+		if d := ch.Message.Content; d != "" {
+			sb.WriteString(d)
+			if onDelta != nil {
+				onDelta(d)
+			}
+		}
+		res.ToolCalls = append(res.ToolCalls, ollamaToolCalls(ch.Message.ToolCalls, len(res.ToolCalls))...)
+		if ch.Done {
+			res.FinishReason = ch.DoneReason
+			res.Usage = ch.usage()
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return res, transportError(ctx, "ollama", err)
+	}
+
+	res.Text = strings.TrimSpace(sb.String())
+	if res.Text == "" && len(res.ToolCalls) == 0 {
+		return res, newError("ollama", KindEmptyOutput, "empty output (done_reason=%q)", res.FinishReason)
+	}
+	return res, nil
+}
+
+func (o *Ollama) payload(r Request, stream bool) map[string]any {
+	msgs := make([]map[string]any, 0, len(r.Messages))
+	for _, m := range r.Messages {
+		msg := map[string]any{"role": m.Role, "content": m.Content}
+		if len(m.Attachments) > 0 {
+			images := make([]string, 0, len(m.Attachments))
+			for _, a := range m.Attachments {
+				images = append(images, a.base64())
+			}
+			msg["images"] = images
+		}
+		if m.Role == RoleTool && m.Name != "" {
+			msg["tool_name"] = m.Name
+		}
+		if len(m.ToolCalls) > 0 {
+			calls := make([]map[string]any, 0, len(m.ToolCalls))
+			for _, tc := range m.ToolCalls {
+				calls = append(calls, map[string]any{"function": map[string]any{"name": tc.Name, "arguments": tc.args()}})
+			}
+			msg["tool_calls"] = calls
+		}
+		msgs = append(msgs, msg)
+	}
+	opts := map[string]any{}
+	for k, v := range o.Options {
+		opts[k] = v
+	}
+	if r.MaxTokens > 0 {
+		opts["num_predict"] = r.Reasoning.outputBudget(r.MaxTokens)
+	}
+	r.Sampling.putChat(opts)
+	payload := map[string]any{
+		"model":    o.Model,
+		"messages": msgs,
+		"stream":   stream,
+	}
+	if len(opts) > 0 {
+		payload["options"] = opts
+	}
+	// Ollama only switches thinking on or off; levels are model specific.
+	if r.Reasoning != nil {
+		payload["think"] = r.Reasoning.budget() != 0
+	}
+	if len(r.Tools) > 0 {
+		payload["tools"] = chatTools(r.Tools)
+	}
+	if r.Output != nil {
+		payload["format"] = r.Output.Schema
+	}
+	if ka := strings.TrimSpace(o.KeepAlive); ka != "" {
+		if n, err := strconv.Atoi(ka); err == nil {
+			payload["keep_alive"] = n
+		} else {
+			payload["keep_alive"] = ka
+		}
+	}
+	return payload
+}
+
+func (o *Ollama) newRequest(ctx context.Context, payload map[string]any) (*http.Request, error) {
+	if strings.TrimSpace(o.Model) == "" {
+		return nil, newError("ollama", KindInvalidRequest, "model missing")
+	}
+	b, _ := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL()+"/api/chat", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+func (o *Ollama) SetRetryPolicy(p RetryPolicy) { o.Retry = p }
@@ -4,8 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"io"
 	"net/http"
 	"os"
@@ -20,6 +18,7 @@ type OpenAI struct {
 	Model string
 	Key   string
 	HTTP  *http.Client
+	Retry RetryPolicy
 }
 
 func NewOpenAI(model, key string) Client {
@@ -36,67 +35,66 @@ func (c *OpenAI) ensureHTTP() {
 			timeout = d
 		}
 	}
-	cl := &http.Client{}
-	cl.Timeout = timeout
-	c.HTTP = cl
+	c.HTTP = newHTTPClient("openai", timeout)
 }
 
-// (text, meta, error). meta 未使用，返回 ""。
-func (c *OpenAI) Generate(ctx context.Context, prompt string, maxTokens int) (string, string, error) {
-	if c.Key == "" {
-		return "", "", errors.New("openai api key missing")
+// Generate returns the answer text with usage, status and the x-request-id header.
+func (c *OpenAI) Generate(ctx context.Context, r Request) (Result, error) {
+	if c.Key == "" && !replaying() {
+		return Result{}, newError("openai", KindAuth, "api key missing")
 	}
 	c.ensureHTTP()
 
-	payload := map[string]any{
-		"model": c.Model,
-		"input": prompt,
-	}
-	if maxTokens > 0 {
-		payload["max_output_tokens"] = maxTokens
-	}
-
-	bodyBytes, _ := json.Marshal(payload)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.openai.com/v1/responses", bytes.NewReader(bodyBytes))
-	if err != nil {
-		return "", "", err
-	}
-	req.Header.Set("Authorization", "Bearer "+c.Key)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.HTTP.Do(req)
+	resp, attempts, err := c.Retry.do(ctx, c.HTTP, func() (*http.Request, error) {
+		return c.newRequest(ctx, c.payload(r))
+	})
 	if err != nil {
-		return "", "", err
+		return Result{Attempts: attempts}, transportError(ctx, "openai", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", "", fmt.Errorf("openai http %d: %s", resp.StatusCode, string(respBody))
+		return Result{Attempts: attempts}, httpError("openai", resp.StatusCode, respBody)
 	}
 
 	var raw map[string]any
 	if err := json.Unmarshal(respBody, &raw); err != nil {
-		return "", "", fmt.Errorf("openai decode error: %v; body=%s", err, string(respBody))
+		return Result{Attempts: attempts}, newError("openai", KindBadResponse, "decode error: %v; body=%s", err, string(respBody))
+	}
+	res := Result{
+		Attempts:     attempts,
+		FinishReason: openaiFinishReason(raw),
+		RequestID:    firstNonEmpty(resp.Header.Get("x-request-id"), asString(raw["id"])),
+		Usage:        openaiUsage(raw["usage"]),
+		ToolCalls:    openaiToolCalls(raw),
 	}
 
 	// 1) Prefer "output_text"
 	if s, ok := raw["output_text"].(string); ok {
 		if ts := strings.TrimSpace(s); ts != "" {
-			return ts, "", nil
+			res.Text = ts
+			return res, nil
 		}
 	}
 
 	// 2) Common: output[].content[].text
 	if out, ok := raw["output"].([]any); ok {
 		if acc := collectAllText(out); acc != "" {
-			return acc, "", nil
+			res.Text = acc
+			return res, nil
 		}
 	}
 
 	// 3) Last resort: recursive scan for any "text"
 	if acc := collectAllText(raw); acc != "" {
-		return acc, "", nil
+		res.Text = acc
+		return res, nil
+	}
+
+	// Tool calls only: the caller runs them and continues the conversation.
+	if len(res.ToolCalls) > 0 {
+		return res, nil
 	}
 
 	// No usable text → surface diagnostics (status/finish_reasons)
@@ -111,11 +109,238 @@ func (c *OpenAI) Generate(ctx context.Context, prompt string, maxTokens int) (st
 			}
 		}
 	}
-	return "", "", fmt.Errorf("openai empty output (status=%q, finish_reasons=%v)", status, reasons)
+	if err := reasoningExhausted("openai", res); err != nil {
+		return res, err
+	}
+	return res, newError("openai", KindEmptyOutput, "empty output (status=%q, finish_reasons=%v)", status, reasons)
+}
+
+// Stream uses the Responses API with "stream": true and forwards
+// response.output_text.delta events to onDelta. Usage comes from the
+// terminal response.completed / response.incomplete event.
+func (c *OpenAI) Stream(ctx context.Context, r Request, onDelta func(string)) (Result, error) {
+	if c.Key == "" && !replaying() {
+		return Result{}, newError("openai", KindAuth, "api key missing")
+	}
+	c.ensureHTTP()
+
+	payload := c.payload(r)
+	payload["stream"] = true
+	resp, attempts, err := c.Retry.do(ctx, c.HTTP, func() (*http.Request, error) {
+		req, err := c.newRequest(ctx, payload)
+		if err == nil {
+			req.Header.Set("Accept", "text/event-stream")
+		}
+		return req, err
+	})
+	if err != nil {
+		return Result{Attempts: attempts}, transportError(ctx, "openai", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return Result{Attempts: attempts}, httpError("openai", resp.StatusCode, respBody)
+	}
+
+	res := Result{Attempts: attempts, RequestID: resp.Header.Get("x-request-id")}
+	var sb strings.Builder
+	err = readSSE(resp.Body, func(event, data string) error {
+		var ev struct {
+			Type     string         `json:"type"`
+			Delta    string         `json:"delta"`
+			Response map[string]any `json:"response"`
+			Code     string         `json:"code"`
+			Message  string         `json:"message"`
+		}
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return newError("openai", KindBadResponse, "stream decode error: %v; data=%s", err, data)
+		}
+		switch ev.Type {
+		case "response.output_text.delta":
+			if ev.Delta != "" {
+				sb.WriteString(ev.Delta)
+				if onDelta != nil {
+					onDelta(ev.Delta)
+				}
+			}
+		case "response.completed", "response.incomplete":
+			res.FinishReason = openaiFinishReason(ev.Response)
+			res.Usage = openaiUsage(ev.Response["usage"])
+			res.ToolCalls = openaiToolCalls(ev.Response)
+			if res.RequestID == "" {
+				res.RequestID = asString(ev.Response["id"])
+			}
+		case "response.failed":
+			code, msg := "", ""
+			if e, ok := ev.Response["error"].(map[string]any); ok {
+				code, msg = asString(e["code"]), asString(e["message"])
+			}
+			return streamError("openai", code, msg)
+		case "error":
+			return streamError("openai", ev.Code, ev.Message)
+		}
+		return nil
+	})
+	if err != nil {
+		return res, transportError(ctx, "openai", err)
+	}
+
+	res.Text = strings.TrimSpace(sb.String())
+	if res.Text == "" && len(res.ToolCalls) == 0 {
+		if err := reasoningExhausted("openai", res); err != nil {
+			return res, err
+		}
+		return res, newError("openai", KindEmptyOutput, "empty output (status=%q)", res.FinishReason)
+	}
+	return res, nil
+}
+
+// payload maps messages to Responses API input items; system messages keep
+// their role so the model treats them as instructions. Assistant tool calls
+// become function_call items and tool results function_call_output items.
+// Prefix caching is automatic; CacheKey only helps route repeats together.
+func (c *OpenAI) payload(r Request) map[string]any {
+	input := make([]map[string]any, 0, len(r.Messages))
+	for _, m := range r.Messages {
+		if m.Role == RoleTool {
+			input = append(input, map[string]any{
+				"type":    "function_call_output",
+				"call_id": m.ToolCallID,
+				"output":  m.Content,
+			})
+			continue
+		}
+		if len(m.Attachments) > 0 {
+			parts := []map[string]any{{"type": "input_text", "text": m.Content}}
+			for _, a := range m.Attachments {
+				if a.Modality() == ModalityImage {
+					parts = append(parts, map[string]any{"type": "input_image", "image_url": a.dataURL()})
+				} else {
+					parts = append(parts, map[string]any{"type": "input_file", "filename": firstNonEmpty(a.Name, "document.pdf"), "file_data": a.dataURL()})
+				}
+			}
+			input = append(input, map[string]any{"type": "message", "role": m.Role, "content": parts})
+		} else if m.Content != "" || len(m.ToolCalls) == 0 {
+			input = append(input, map[string]any{
+				"type":    "message",
+				"role":    m.Role,
+				"content": m.Content,
+			})
+		}
+		for _, tc := range m.ToolCalls {
+			input = append(input, map[string]any{
+				"type":      "function_call",
+				"call_id":   tc.ID,
+				"name":      tc.Name,
+				"arguments": string(tc.args()),
+			})
+		}
+	}
+	payload := map[string]any{
+		"model": c.Model,
+		"input": input,
+	}
+	if len(r.Tools) > 0 {
+		tools := make([]map[string]any, 0, len(r.Tools))
+		for _, t := range r.Tools {
+			tools = append(tools, map[string]any{
+				"type":        "function",
+				"name":        t.Name,
+				"description": t.Description,
+				"parameters":  t.schema(),
+			})
+		}
+		payload["tools"] = tools
+	}
+	if r.MaxTokens > 0 {
+		payload["max_output_tokens"] = r.Reasoning.outputBudget(r.MaxTokens)
+	}
+	if e := r.Reasoning.effort(); e != "" {
+		payload["reasoning"] = map[string]any{"effort": e}
+	}
+	if s := r.Sampling; s.Temperature != nil {
+		payload["temperature"] = *s.Temperature
+	}
+	if s := r.Sampling; s.TopP != nil {
+		payload["top_p"] = *s.TopP
+	}
+	if r.CacheKey != "" {
+		payload["prompt_cache_key"] = r.CacheKey
+	}
+	if o := r.Output; o != nil {
+		payload["text"] = map[string]any{"format": map[string]any{
+			"type":   "json_schema",
+			"name":   o.name(),
+			"schema": o.Schema,
+			"strict": o.Strict,
+		}}
+	}
+	return payload
+}
+
+func (c *OpenAI) newRequest(ctx context.Context, payload map[string]any) (*http.Request, error) {
+	bodyBytes, _ := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.openai.com/v1/responses", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Key)
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
 }
 
 // ---------- helpers ----------
 
+// openaiFinishReason reports the response status, or the incomplete reason
+// (e.g. "max_output_tokens") when the response was cut short.
+func openaiFinishReason(raw map[string]any) string {
+	if d, ok := raw["incomplete_details"].(map[string]any); ok {
+		if r := asString(d["reason"]); r != "" {
+			return r
+		}
+	}
+	return asString(raw["status"])
+}
+
+// openaiToolCalls collects output[] items of type "function_call".
+func openaiToolCalls(raw map[string]any) []ToolCall {
+	out, _ := raw["output"].([]any)
+	var calls []ToolCall
+	for _, it := range out {
+		m, ok := it.(map[string]any)
+		if !ok || asString(m["type"]) != "function_call" {
+			continue
+		}
+		calls = append(calls, ToolCall{
+			ID:        asString(m["call_id"]),
+			Name:      asString(m["name"]),
+			Arguments: argsFromString(asString(m["arguments"])),
+		})
+	}
+	return calls
+}
+
+func openaiUsage(v any) Usage {
+	var u struct {
+		InputTokens        int `json:"input_tokens"`
+		OutputTokens       int `json:"output_tokens"`
+		InputTokensDetails struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"input_tokens_details"`
+		OutputTokensDetails struct {
+			ReasoningTokens int `json:"reasoning_tokens"`
+		} `json:"output_tokens_details"`
+	}
+	decodeInto(v, &u)
+	return Usage{
+		InputTokens:     u.InputTokens,
+		OutputTokens:    u.OutputTokens,
+		CachedTokens:    u.InputTokensDetails.CachedTokens,
+		ReasoningTokens: u.OutputTokensDetails.ReasoningTokens,
+	}
+}
+
 func collectAllText(v any) string {
 	var buf []string
 	var walk func(any)
@@ -162,3 +387,26 @@ func asString(v any) string {
 	}
 	return ""
 }
+
+// decodeInto converts a generically decoded JSON value into dst (best effort).
+func decodeInto(v any, dst any) {
+	if v == nil {
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(b, dst)
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func (c *OpenAI) SetRetryPolicy(p RetryPolicy) { c.Retry = p }
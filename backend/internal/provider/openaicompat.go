@@ -0,0 +1,328 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// OpenAICompat talks to any server implementing OpenAI's Chat Completions API
+// (vLLM, llama.cpp server, LiteLLM, ...).
+// POST {BaseURL}/chat/completions, where BaseURL usually ends in "/v1".
+// Key is optional; when set it is sent as a Bearer token. Headers are added verbatim.
+type OpenAICompat struct {
+	Model   string
+	BaseURL string
+	Key     string
+	Headers map[string]string
+	HTTP    *http.Client
+	Retry   RetryPolicy
+}
+
+func NewOpenAICompat(model, baseURL, key string, headers map[string]string) Client {
+	return &OpenAICompat{Model: model, BaseURL: baseURL, Key: key, Headers: headers}
+}
+
+func (c *OpenAICompat) ensureHTTP() {
+	if c.HTTP != nil {
+		return
+	}
+	// Self-hosted models are often slower than hosted APIs, hence the larger default.
+	timeout := 60 * time.Second
+	if t := os.Getenv("OPENAI_COMPAT_HTTP_TIMEOUT"); t != "" {
+		if d, err := time.ParseDuration(t); err == nil {
+			timeout = d
+		}
+	}
+	c.HTTP = newHTTPClient("openai-compatible", timeout)
+}
+
+func (c *OpenAICompat) Generate(ctx context.Context, r Request) (Result, error) {
+	if strings.TrimSpace(c.BaseURL) == "" {
+		return Result{}, newError("openai-compatible", KindInvalidRequest, "base url missing")
+	}
+	c.ensureHTTP()
+
+	resp, attempts, err := c.Retry.do(ctx, c.HTTP, func() (*http.Request, error) {
+		return c.newRequest(ctx, c.payload(r))
+	})
+	if err != nil {
+		return Result{Attempts: attempts}, transportError(ctx, "openai-compatible", err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Result{Attempts: attempts}, httpError("openai-compatible", resp.StatusCode, raw)
+	}
+
+	var jr struct {
+		ID      string `json:"id"`
+		Choices []struct {
+			Message struct {
+				Content   string         `json:"content"`
+				ToolCalls []chatToolCall `json:"tool_calls"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage *chatUsage `json:"usage"`
+	}
+	if err := json.Unmarshal(raw, &jr); err != nil {
+		return Result{Attempts: attempts}, newError("openai-compatible", KindBadResponse, "decode error: %v; body=%s", err, string(raw))
+	}
+	res := Result{Attempts: attempts, RequestID: firstNonEmpty(resp.Header.Get("x-request-id"), jr.ID)}
+	if jr.Usage != nil {
+		res.Usage = jr.Usage.toUsage()
+	}
+	if len(jr.Choices) > 0 {
+		res.FinishReason = jr.Choices[0].FinishReason
+		res.Text = strings.TrimSpace(jr.Choices[0].Message.Content)
+		for _, tc := range jr.Choices[0].Message.ToolCalls {
+			res.ToolCalls = append(res.ToolCalls, tc.toToolCall())
+		}
+	}
+	if res.Text == "" && len(res.ToolCalls) == 0 {
+		return res, newError("openai-compatible", KindEmptyOutput, "empty output (finish_reason=%q)", res.FinishReason)
+	}
+	return res, nil
+}
+
+// Stream sets "stream": true and forwards choices[0].delta.content chunks.
+// Usage is requested via stream_options and arrives in the last chunk on
+// servers that support it.
+func (c *OpenAICompat) Stream(ctx context.Context, r Request, onDelta func(string)) (Result, error) {
+	if strings.TrimSpace(c.BaseURL) == "" {
+		return Result{}, newError("openai-compatible", KindInvalidRequest, "base url missing")
+	}
+	c.ensureHTTP()
+
+	payload := c.payload(r)
+	payload["stream"] = true
+	payload["stream_options"] = map[string]any{"include_usage": true}
+	resp, attempts, err := c.Retry.do(ctx, c.HTTP, func() (*http.Request, error) {
+		req, err := c.newRequest(ctx, payload)
+		if err == nil {
+			req.Header.Set("Accept", "text/event-stream")
+		}
+		return req, err
+	})
+	if err != nil {
+		return Result{Attempts: attempts}, transportError(ctx, "openai-compatible", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		raw, _ := io.ReadAll(resp.Body)
+		return Result{Attempts: attempts}, httpError("openai-compatible", resp.StatusCode, raw)
+	}
+
+	res := Result{Attempts: attempts, RequestID: resp.Header.Get("x-request-id")}
+	var sb strings.Builder
+	// Tool call deltas carry the id and name once, then argument fragments,
+	// keyed by their index within the message.
+	var calls []*chatToolCall
+	err = readSSE(resp.Body, func(event, data string) error {
+		if strings.TrimSpace(data) == "[DONE]" {
+			return nil
+		}
+		var ch struct {
+			ID      string `json:"id"`
+			Choices []struct {
+				Delta struct {
+					Content   string         `json:"content"`
+					ToolCalls []chatToolCall `json:"tool_calls"`
+				} `json:"delta"`
+				FinishReason *string `json:"finish_reason"`
+			} `json:"choices"`
+			Usage *chatUsage `json:"usage"`
+			Error *struct {
+				Message string `json:"message"`
+				Type    string `json:"type"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &ch); err != nil {
+			return newError("openai-compatible", KindBadResponse, "stream decode error: %v; data=%s", err, data)
+		}
+		if ch.Error != nil {
+			return streamError("openai-compatible", ch.Error.Type, ch.Error.Message)
+		}
+		if res.RequestID == "" {
+			res.RequestID = ch.ID
+		}
+		if ch.Usage != nil {
+			res.Usage = ch.Usage.toUsage()
+		}
+		for _, choice := range ch.Choices {
+			if choice.FinishReason != nil && *choice.FinishReason != "" {
+				res.FinishReason = *choice.FinishReason
+			}
+			if d := choice.Delta.Content; d != "" {
+				sb.WriteString(d)
+				if onDelta != nil {
+					onDelta(d)
+				}
+			}
+			for _, d := range choice.Delta.ToolCalls {
+				i := len(calls)
+				if d.Index != nil {
+					i = *d.Index
+				}
+				for len(calls) <= i {
+					calls = append(calls, &chatToolCall{})
+				}
+				tc := calls[i]
+				if d.ID != "" {
+					tc.ID = d.ID
+				}
+				if d.Function.Name != "" {
+					tc.Function.Name = d.Function.Name
+				}
+				tc.Function.Arguments += d.Function.Arguments
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return res, transportError(ctx, "openai-compatible", err)
+	}
+
+	for _, tc := range calls {
+		res.ToolCalls = append(res.ToolCalls, tc.toToolCall())
+	}
+	res.Text = strings.TrimSpace(sb.String())
+	if res.Text == "" && len(res.ToolCalls) == 0 {
+		return res, newError("openai-compatible", KindEmptyOutput, "empty output (finish_reason=%q)", res.FinishReason)
+	}
+	return res, nil
+}
+
+// payload maps messages 1:1 to Chat Completions messages; the role names match.
+func (c *OpenAICompat) payload(r Request) map[string]any {
+	msgs := make([]map[string]any, 0, len(r.Messages))
+	for _, m := range r.Messages {
+		msg := map[string]any{"role": m.Role, "content": m.Content}
+		if len(m.Attachments) > 0 {
+			parts := []map[string]any{{"type": "text", "text": m.Content}}
+			for _, a := range m.Attachments {
+				parts = append(parts, map[string]any{"type": "image_url", "image_url": map[string]any{"url": a.dataURL()}})
+			}
+			msg["content"] = parts
+		}
+		switch {
+		case m.Role == RoleTool:
+			msg["tool_call_id"] = m.ToolCallID
+		case len(m.ToolCalls) > 0:
+			calls := make([]chatToolCall, 0, len(m.ToolCalls))
+			for _, tc := range m.ToolCalls {
+				ct := chatToolCall{ID: tc.ID, Type: "function"}
+				ct.Function.Name = tc.Name
+				ct.Function.Arguments = string(tc.args())
+				calls = append(calls, ct)
+			}
+			msg["tool_calls"] = calls
+		}
+		msgs = append(msgs, msg)
+	}
+	payload := map[string]any{
+		"model":    c.Model,
+		"messages": msgs,
+	}
+	if r.MaxTokens > 0 {
+		payload["max_tokens"] = r.Reasoning.outputBudget(r.MaxTokens)
+	}
+	if e := r.Reasoning.effort(); e != "" {
+		payload["reasoning_effort"] = e
+	}
+	r.Sampling.putChat(payload)
+	if len(r.Tools) > 0 {
+		payload["tools"] = chatTools(r.Tools)
+	}
+	if o := r.Output; o != nil {
+		payload["response_format"] = map[string]any{
+			"type": "json_schema",
+			"json_schema": map[string]any{
+				"name":   o.name(),
+				"schema": o.Schema,
+				"strict": o.Strict,
+			},
+		}
+	}
+	return payload
+}
+
+// chatToolCall is the Chat Completions tool call shape, shared by requests,
+// responses and stream deltas (which also carry Index).
+type chatToolCall struct {
+	Index    *int   `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+func (tc chatToolCall) toToolCall() ToolCall {
+	return ToolCall{ID: tc.ID, Name: tc.Function.Name, Arguments: argsFromString(tc.Function.Arguments)}
+}
+
+// chatTools declares tools in the Chat Completions format.
+func chatTools(tools []Tool) []map[string]any {
+	out := make([]map[string]any, 0, len(tools))
+	for _, t := range tools {
+		out = append(out, map[string]any{
+			"type": "function",
+			"function": map[string]any{
+				"name":        t.Name,
+				"description": t.Description,
+				"parameters":  t.schema(),
+			},
+		})
+	}
+	return out
+}
+
+func (c *OpenAICompat) newRequest(ctx context.Context, payload map[string]any) (*http.Request, error) {
+	b, _ := json.Marshal(payload)
+	url := strings.TrimRight(c.BaseURL, "/") + "/chat/completions"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.Key != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Key)
+	}
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
+
+// chatUsage is the Chat Completions usage block.
+type chatUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+	CompletionTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details"`
+}
+
+func (u chatUsage) toUsage() Usage {
+	return Usage{
+		InputTokens:     u.PromptTokens,
+		OutputTokens:    u.CompletionTokens,
+		CachedTokens:    u.PromptTokensDetails.CachedTokens,
+		ReasoningTokens: u.CompletionTokensDetails.ReasoningTokens,
+	}
+}
+
+func (c *OpenAICompat) SetRetryPolicy(p RetryPolicy) { c.Retry = p }
@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// OutputSchema asks for a JSON answer matching Schema (a JSON Schema object).
+// Result.Text then holds the JSON document. How it is enforced depends on the
+// API: OpenAI text.format json_schema, Chat Completions response_format,
+// Gemini responseSchema, Ollama format, and a forced tool call on Anthropic.
+type OutputSchema struct {
+	Name   string          `json:"name,omitempty"`   // identifier sent to the API; default "response"
+	Schema json.RawMessage `json:"schema"`           // JSON Schema object
+	Strict bool            `json:"strict,omitempty"` // OpenAI strict mode: every object must be closed and fully required
+}
+
+func (o *OutputSchema) name() string {
+	if o == nil || strings.TrimSpace(o.Name) == "" {
+		return "response"
+	}
+	return o.Name
+}
+
+// outputTool is the tool Anthropic is forced to call to produce structured output.
+func (o *OutputSchema) outputTool() Tool {
+	return Tool{Name: o.name(), Description: "Respond with the final answer as structured data.", Parameters: o.Schema}
+}
+
+// takeOutputCall moves the call of the structured-output tool (if any) from
+// res.ToolCalls into res.Text.
+func takeOutputCall(r Request, res *Result) {
+	if r.Output == nil {
+		return
+	}
+	name := r.Output.name()
+	kept := res.ToolCalls[:0]
+	for _, tc := range res.ToolCalls {
+		if tc.Name == name {
+			res.Text = string(tc.args())
+			continue
+		}
+		kept = append(kept, tc)
+	}
+	res.ToolCalls = kept
+	if len(kept) == 0 {
+		res.ToolCalls = nil
+	}
+}
+
+// geminiSchema drops JSON Schema keywords that Gemini's OpenAPI-style
+// responseSchema rejects.
+func geminiSchema(raw json.RawMessage) any {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return raw
+	}
+	var strip func(any)
+	strip = func(x any) {
+		switch t := x.(type) {
+		case map[string]any:
+			delete(t, "additionalProperties")
+			delete(t, "$schema")
+			for _, c := range t {
+				strip(c)
+			}
+		case []any:
+			for _, c := range t {
+				strip(c)
+			}
+		}
+	}
+	strip(v)
+	return v
+}
@@ -1,28 +1,153 @@
 package provider
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
 
 // Client is a minimal LLM provider interface.
 type Client interface {
-    // Generate sends a single user instruction and returns (answer, finishReason, error).
-    Generate(ctx context.Context, instruction string, maxTokens int) (string, string, error)
+	// Generate sends the conversation in req and returns the answer with its metadata.
+	// On error the Result may still carry usage and the request ID when the API reported them.
+	Generate(ctx context.Context, req Request) (Result, error)
+}
+
+// Streamer is implemented by clients that can emit the answer incrementally.
+type Streamer interface {
+	// Stream behaves like Generate but calls onDelta for every text fragment as it arrives.
+	// The returned Result.Text is the full concatenated text.
+	Stream(ctx context.Context, req Request, onDelta func(string)) (Result, error)
+}
+
+// Usage is the token accounting of one call. InputTokens includes cached
+// prompt tokens; CachedTokens is the part served from the provider's cache.
+// Likewise OutputTokens includes ReasoningTokens, the hidden thinking
+// (not reported by Anthropic and Ollama).
+type Usage struct {
+	InputTokens      int `json:"input_tokens"`
+	OutputTokens     int `json:"output_tokens"`
+	CachedTokens     int `json:"cached_tokens"`
+	CacheWriteTokens int `json:"cache_write_tokens"`
+	ReasoningTokens  int `json:"reasoning_tokens"`
+}
+
+// Add accumulates o into u.
+func (u *Usage) Add(o Usage) {
+	u.InputTokens += o.InputTokens
+	u.OutputTokens += o.OutputTokens
+	u.CachedTokens += o.CachedTokens
+	u.CacheWriteTokens += o.CacheWriteTokens
+	u.ReasoningTokens += o.ReasoningTokens
+}
+
+// Result is the outcome of one Generate/Stream call.
+type Result struct {
+	Text         string `json:"text"`
+	FinishReason string `json:"finish_reason,omitempty"`
+	RequestID    string `json:"request_id,omitempty"`
+	Usage        Usage  `json:"usage"`
+	Attempts     int    `json:"attempts,omitempty"` // HTTP attempts made, including retries
+
+	// ToolCalls are the functions the model wants called before it answers.
+	// A result with tool calls may have empty Text.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// Thinking holds opaque reasoning blocks (Anthropic) that must be sent
+	// back with ToolCalls when the conversation continues.
+	Thinking []json.RawMessage `json:"thinking,omitempty"`
+}
+
+// Message roles understood by every client.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool" // result of a tool call; see ToolResult
+)
+
+// Message is one turn of a conversation.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+
+	ToolCalls []ToolCall        `json:"tool_calls,omitempty"` // assistant: calls it made
+	Thinking  []json.RawMessage `json:"thinking,omitempty"`   // assistant: Result.Thinking of that turn
+
+	Attachments []Attachment `json:"attachments,omitempty"`  // user: images/documents; see CheckAttachments
+	ToolCallID  string       `json:"tool_call_id,omitempty"` // tool: the call being answered
+	Name        string       `json:"name,omitempty"`         // tool: the function name
+
+	Cache bool `json:"cache,omitempty"` // prompt-cache breakpoint after this message; see cache.go
+}
+
+// Request is a provider-neutral generation request. System messages may appear
+// anywhere in Messages; clients hoist them into the API's dedicated system field.
+type Request struct {
+	Messages  []Message     `json:"messages"`
+	MaxTokens int           `json:"max_tokens"`
+	Tools     []Tool        `json:"tools,omitempty"`
+	Output    *OutputSchema `json:"output,omitempty"` // nil = free text
+	Sampling  Sampling      `json:"sampling"`
+	Reasoning *Reasoning    `json:"reasoning,omitempty"` // nil = provider default
+	CacheKey  string        `json:"cache_key,omitempty"` // groups requests sharing a cached prefix (OpenAI)
+}
+
+// Prompt builds a single-turn request from a flat user instruction.
+func Prompt(instruction string, maxTokens int) Request {
+	return Request{
+		Messages:  []Message{{Role: RoleUser, Content: instruction}},
+		MaxTokens: maxTokens,
+	}
+}
+
+// System returns all system messages joined by a blank line.
+func (r Request) System() string {
+	var parts []string
+	for _, m := range r.Messages {
+		if m.Role == RoleSystem && strings.TrimSpace(m.Content) != "" {
+			parts = append(parts, m.Content)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// Turns returns the user/assistant messages in order, without system messages.
+func (r Request) Turns() []Message {
+	out := make([]Message, 0, len(r.Messages))
+	for _, m := range r.Messages {
+		if m.Role != RoleSystem {
+			out = append(out, m)
+		}
+	}
+	return out
 }
 
 type Keys struct {
-    OpenAI    string
-    Google    string
-    Anthropic string
+	OpenAI    string
+	Google    string
+	Anthropic string
+}
+
+// For returns the key in k for the named built-in provider (aliases included).
+func (k Keys) For(provider string) string {
+	s, _ := Lookup(provider)
+	switch s.Name {
+	case "openai":
+		return k.OpenAI
+	case "gemini":
+		return k.Google
+	case "anthropic":
+		return k.Anthropic
+	}
+	return ""
 }
 
+// NewClient builds a client with default settings through the registry;
+// unknown providers (or missing required settings) yield a Null client.
 func NewClient(provider, model string, keys Keys) Client {
-    switch provider {
-    case "openai":
-        return &OpenAI{Model: model, Key: keys.OpenAI}
-    case "gemini", "google":
-        return &Gemini{Model: model, Key: keys.Google}
-    case "anthropic", "claude":
-        return &Anthropic{Model: model, Key: keys.Anthropic}
-    default:
-        return &Null{}
-    }
+	cl, err := Build(provider, Config{Model: model, APIKey: keys.For(provider)})
+	if err != nil {
+		return &Null{}
+	}
+	return cl
 }
@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reasoning controls thinking on reasoning models. MaxTokens stays the budget
+// for the visible answer; the thinking budget is added on top of it so a long
+// deliberation cannot starve the answer.
+//
+// OpenAI sends Effort as reasoning.effort, Anthropic BudgetTokens as
+// thinking.budget_tokens and Gemini as thinkingConfig.thinkingBudget. When only
+// one of the two is set the other is derived from it.
+type Reasoning struct {
+	Effort       string `json:"effort,omitempty"`        // none | minimal | low | medium | high
+	BudgetTokens *int   `json:"budget_tokens,omitempty"` // 0 disables thinking where possible; -1 = dynamic (Gemini)
+}
+
+var effortBudgets = map[string]int{
+	"none":    0,
+	"minimal": 1024,
+	"low":     2048,
+	"medium":  8192,
+	"high":    24576,
+}
+
+// budget returns the thinking budget in tokens (-1 = let the model decide).
+func (r *Reasoning) budget() int {
+	if r == nil {
+		return 0
+	}
+	if r.BudgetTokens != nil {
+		return *r.BudgetTokens
+	}
+	if b, ok := effortBudgets[r.effort()]; ok {
+		return b
+	}
+	return -1
+}
+
+// effort returns the effort level, derived from the budget when unset.
+func (r *Reasoning) effort() string {
+	if r == nil {
+		return ""
+	}
+	if e := strings.ToLower(strings.TrimSpace(r.Effort)); e != "" {
+		return e
+	}
+	if r.BudgetTokens == nil {
+		return ""
+	}
+	switch b := *r.BudgetTokens; {
+	case b == 0:
+		return "minimal"
+	case b < 0:
+		return "medium"
+	case b <= effortBudgets["low"]:
+		return "low"
+	case b <= effortBudgets["medium"]:
+		return "medium"
+	default:
+		return "high"
+	}
+}
+
+// outputBudget is the API's output cap: the visible answer plus thinking.
+func (r *Reasoning) outputBudget(maxTokens int) int {
+	if b := r.budget(); b > 0 && maxTokens > 0 {
+		return maxTokens + b
+	}
+	return maxTokens
+}
+
+// ValidateReasoning checks r against the provider's Capabilities. Providers
+// with a ThinkingMinBudget (Anthropic) also reject temperature (other than 1)
+// and top_k while thinking, so s is checked as well.
+func ValidateReasoning(provider string, r *Reasoning, s Sampling) error {
+	if r == nil {
+		return nil
+	}
+	if e := strings.ToLower(strings.TrimSpace(r.Effort)); e != "" {
+		if _, ok := effortBudgets[e]; !ok {
+			return fmt.Errorf("reasoning effort %q must be one of none, minimal, low, medium, high", r.Effort)
+		}
+	}
+	caps, ok := capabilities(provider)
+	if !ok {
+		return nil
+	}
+	if !caps.Reasoning {
+		return fmt.Errorf("provider %q does not support reasoning controls", provider)
+	}
+	if b := r.BudgetTokens; b != nil && (*b < -1 || (*b == -1 && !caps.DynamicThinking)) {
+		return fmt.Errorf("reasoning budget_tokens must be >= 0, got %d", *b)
+	}
+	if min := caps.ThinkingMinBudget; min > 0 {
+		b := r.budget()
+		if b > 0 && b < min {
+			return fmt.Errorf("provider %q needs thinking budget_tokens >= %d, got %d", provider, min, b)
+		}
+		if b > 0 {
+			if t := s.Temperature; t != nil && *t != 1 {
+				return fmt.Errorf("thinking requires temperature 1 or unset, got %g", *t)
+			}
+			if s.TopK != nil {
+				return fmt.Errorf("thinking does not allow top_k")
+			}
+		}
+	}
+	return nil
+}
+
+// reasoningExhausted explains an empty answer whose output budget went
+// entirely to thinking; nil when that is not what happened.
+func reasoningExhausted(provider string, res Result) error {
+	u := res.Usage
+	if u.ReasoningTokens == 0 || u.ReasoningTokens < u.OutputTokens {
+		return nil
+	}
+	return newError(provider, KindEmptyOutput,
+		"empty output: all %d output tokens were spent on reasoning (finish_reason=%q); raise max_tokens or reasoning.budget_tokens, or lower reasoning.effort",
+		u.ReasoningTokens, res.FinishReason)
+}
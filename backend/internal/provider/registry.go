@@ -0,0 +1,195 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Spec describes a provider: how to build its client, the names it answers
+// to, which settings it reads and what it can do. Built-in providers are
+// registered in builtin.go; other packages can add their own with Register,
+// typically from an init function.
+type Spec struct {
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases,omitempty"`
+	Description string   `json:"description,omitempty"`
+
+	// KeyEnv is the env var holding the default API key, used when the
+	// runner sets no key of its own.
+	KeyEnv string `json:"key_env,omitempty"`
+
+	// Fields is the config schema: the Config fields the factory reads.
+	Fields []ConfigField `json:"fields"`
+
+	Capabilities Capabilities `json:"capabilities"`
+
+	// New builds a client; Config has been checked against Fields.
+	New func(Config) (Client, error) `json:"-"`
+}
+
+// ConfigField documents one setting of a provider. Name is the Config /
+// runner JSON field name (model, api_key, base_url, headers, options,
+// keep_alive, fixture, command, env, dir, timeout, template).
+type ConfigField struct {
+	Name        string `json:"name"`
+	Required    bool   `json:"required,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Capabilities are the features a provider's API supports. They drive
+// config validation (sampling, reasoning) and request routing (attachments).
+type Capabilities struct {
+	Streaming        bool     `json:"streaming"`
+	Tools            bool     `json:"tools"`
+	StructuredOutput bool     `json:"structured_output"`
+	Reasoning        bool     `json:"reasoning"`
+	Embeddings       bool     `json:"embeddings"`           // the client implements Embedder
+	Modalities       []string `json:"modalities,omitempty"` // attachment modalities (ModalityImage, ModalityDocument)
+
+	Sampling       []string `json:"sampling,omitempty"`        // supported Sampling fields, by JSON name
+	MaxTemperature float64  `json:"max_temperature,omitempty"` // 0 = 2
+	MaxStop        int      `json:"max_stop,omitempty"`        // 0 = no limit
+
+	// ThinkingMinBudget > 0 means thinking needs at least that many budget
+	// tokens and excludes temperature (other than 1) and top_k (Anthropic).
+	ThinkingMinBudget int  `json:"thinking_min_budget,omitempty"`
+	DynamicThinking   bool `json:"dynamic_thinking,omitempty"` // budget_tokens -1 lets the model decide (Gemini)
+}
+
+// Config holds the per-runner settings handed to a provider factory.
+type Config struct {
+	Model     string
+	APIKey    string
+	BaseURL   string
+	Headers   map[string]string
+	Options   map[string]any
+	KeepAlive string
+	Fixture   string
+
+	// Local command settings (exec provider).
+	Command []string
+	Env     map[string]string
+	Dir     string
+	Timeout time.Duration
+
+	Template *HTTPTemplate // templated HTTP provider
+}
+
+func (c Config) has(field string) bool {
+	switch field {
+	case "model":
+		return strings.TrimSpace(c.Model) != ""
+	case "api_key":
+		return c.APIKey != ""
+	case "base_url":
+		return strings.TrimSpace(c.BaseURL) != ""
+	case "headers":
+		return len(c.Headers) > 0
+	case "options":
+		return len(c.Options) > 0
+	case "keep_alive":
+		return strings.TrimSpace(c.KeepAlive) != ""
+	case "fixture":
+		return strings.TrimSpace(c.Fixture) != ""
+	case "command":
+		return len(c.Command) > 0 && strings.TrimSpace(c.Command[0]) != ""
+	case "env":
+		return len(c.Env) > 0
+	case "dir":
+		return c.Dir != ""
+	case "timeout":
+		return c.Timeout > 0
+	case "template":
+		return c.Template != nil
+	}
+	return false
+}
+
+var registry = struct {
+	sync.RWMutex
+	specs map[string]*Spec // canonical name → spec
+	names map[string]*Spec // lower-cased name or alias → spec
+}{specs: map[string]*Spec{}, names: map[string]*Spec{}}
+
+func normName(s string) string { return strings.ToLower(strings.TrimSpace(s)) }
+
+// Register adds a provider. Names and aliases are case-insensitive and must
+// not clash with an existing registration.
+func Register(s Spec) error {
+	if normName(s.Name) == "" || s.New == nil {
+		return fmt.Errorf("provider spec needs a name and a factory")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	names := append([]string{s.Name}, s.Aliases...)
+	for _, n := range names {
+		if _, dup := registry.names[normName(n)]; dup {
+			return fmt.Errorf("provider name %q already registered", n)
+		}
+	}
+	sp := &s
+	registry.specs[s.Name] = sp
+	for _, n := range names {
+		registry.names[normName(n)] = sp
+	}
+	return nil
+}
+
+// MustRegister is Register for init functions; it panics on error.
+func MustRegister(s Spec) {
+	if err := Register(s); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup finds a provider by name or alias.
+func Lookup(name string) (Spec, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	sp, ok := registry.names[normName(name)]
+	if !ok {
+		return Spec{}, false
+	}
+	return *sp, true
+}
+
+// Providers returns all registered providers sorted by name.
+func Providers() []Spec {
+	registry.RLock()
+	defer registry.RUnlock()
+	out := make([]Spec, 0, len(registry.specs))
+	for _, sp := range registry.specs {
+		out = append(out, *sp)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Build creates a client for the named provider. A missing API key falls
+// back to the provider's KeyEnv; required fields are checked before the
+// factory runs.
+func Build(name string, cfg Config) (Client, error) {
+	s, ok := Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q", name)
+	}
+	if cfg.APIKey == "" && s.KeyEnv != "" {
+		cfg.APIKey = os.Getenv(s.KeyEnv)
+	}
+	for _, f := range s.Fields {
+		if f.Required && !cfg.has(f.Name) {
+			return nil, fmt.Errorf("provider %q requires %s", name, f.Name)
+		}
+	}
+	return s.New(cfg)
+}
+
+// capabilities returns the capabilities of a registered provider.
+func capabilities(name string) (Capabilities, bool) {
+	s, ok := Lookup(name)
+	return s.Capabilities, ok
+}
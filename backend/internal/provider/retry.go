@@ -0,0 +1,171 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how a client retries failed HTTP calls.
+// The zero value means DefaultRetryPolicy; MaxAttempts 1 disables retries.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts, including the first
+	BaseDelay   time.Duration // first backoff step; doubles per attempt
+	MaxDelay    time.Duration // cap for computed backoff
+}
+
+// DefaultRetryPolicy is used when a client has no explicit policy.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 500 * time.Millisecond, MaxDelay: 8 * time.Second}
+
+// RetrySetter is implemented by clients whose HTTP calls honor a RetryPolicy.
+type RetrySetter interface {
+	SetRetryPolicy(RetryPolicy)
+}
+
+func (p RetryPolicy) normalized() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultRetryPolicy.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultRetryPolicy.MaxDelay
+	}
+	if p.MaxDelay < p.BaseDelay {
+		p.MaxDelay = p.BaseDelay
+	}
+	return p
+}
+
+// do sends the request produced by newReq (called once per attempt so the
+// body can be replayed) and retries transport errors and retryable statuses,
+// unless the error body marks the failure as permanent (permanentFailure).
+// It waits for the server's hint (Retry-After, retry-after-ms, exhausted
+// anthropic-ratelimit-* resets) or exponential backoff with full jitter.
+// A retry is only attempted if the wait fits before ctx's deadline; otherwise
+// the last response/error is returned as-is, so callers report the original failure.
+// The returned response (if any) has an unread body owned by the caller.
+func (p RetryPolicy) do(ctx context.Context, hc *http.Client, newReq func() (*http.Request, error)) (*http.Response, int, error) {
+	p = p.normalized()
+	for attempt := 1; ; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return nil, attempt, err
+		}
+		resp, err := hc.Do(req)
+
+		var wait time.Duration
+		if err != nil {
+			var pe *Error
+			if ctx.Err() != nil || attempt >= p.MaxAttempts || (errors.As(err, &pe) && !pe.Retryable) {
+				return nil, attempt, err
+			}
+			wait = p.backoff(attempt)
+		} else {
+			if !retryableStatus(resp.StatusCode) || attempt >= p.MaxAttempts || permanentFailure(resp) {
+				return resp, attempt, nil
+			}
+			wait = serverRetryHint(resp.Header, time.Now())
+			if wait <= 0 {
+				wait = p.backoff(attempt)
+			}
+		}
+		if !fitsDeadline(ctx, wait, p.MaxDelay) {
+			return resp, attempt, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, attempt, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// permanentFailure peeks at the body of a retryable status and reports
+// whether httpError classifies it as not retryable (e.g. 429
+// insufficient_quota). The body stays readable by the caller.
+func permanentFailure(resp *http.Response) bool {
+	peek, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peek), resp.Body), resp.Body}
+	return err == nil && !httpError("", resp.StatusCode, peek).Retryable
+}
+
+// backoff returns a full-jitter delay for the given (1-based) attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
+
+// fitsDeadline reports whether waiting d still leaves time for another attempt.
+// Without a deadline, waits longer than 4*maxDelay are refused so a far-off
+// server hint cannot park a runner indefinitely.
+func fitsDeadline(ctx context.Context, d, maxDelay time.Duration) bool {
+	if dl, ok := ctx.Deadline(); ok {
+		return time.Until(dl) > d
+	}
+	return d <= 4*maxDelay
+}
+
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+		529: // Anthropic "overloaded"
+		return true
+	}
+	return false
+}
+
+// serverRetryHint extracts how long the server asked us to wait; 0 when unknown.
+func serverRetryHint(h http.Header, now time.Time) time.Duration {
+	if v := strings.TrimSpace(h.Get("retry-after-ms")); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms > 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	if v := strings.TrimSpace(h.Get("Retry-After")); v != "" {
+		if secs, err := strconv.ParseFloat(v, 64); err == nil && secs > 0 {
+			return time.Duration(secs * float64(time.Second))
+		}
+		if t, err := http.ParseTime(v); err == nil && t.After(now) {
+			return t.Sub(now)
+		}
+	}
+	// Anthropic: for every exhausted bucket, wait until its reset (RFC 3339).
+	var wait time.Duration
+	for _, kind := range []string{"requests", "tokens", "input-tokens", "output-tokens"} {
+		if strings.TrimSpace(h.Get("anthropic-ratelimit-"+kind+"-remaining")) != "0" {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, strings.TrimSpace(h.Get("anthropic-ratelimit-"+kind+"-reset"))); err == nil {
+			if d := t.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	return wait
+}
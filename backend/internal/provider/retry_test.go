@@ -0,0 +1,243 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// scriptedServer answers successive requests with the given statuses (the
+// last one repeats), setting hdr on every non-2xx response.
+func scriptedServer(t *testing.T, statuses []int, hdr http.Header) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1)) - 1
+		status := statuses[min(n, len(statuses)-1)]
+		if status >= 300 {
+			for k, v := range hdr {
+				w.Header()[k] = v
+			}
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	fast := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	// Without a deadline, hints up to 4*MaxDelay are waited for.
+	hinted := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	tests := []struct {
+		name         string
+		policy       RetryPolicy
+		statuses     []int
+		header       http.Header
+		timeout      time.Duration
+		wantStatus   int
+		wantAttempts int
+		minElapsed   time.Duration
+	}{
+		{name: "success first try", policy: fast, statuses: []int{200}, wantStatus: 200, wantAttempts: 1},
+		{name: "retries 503 then succeeds", policy: fast, statuses: []int{503, 503, 200}, wantStatus: 200, wantAttempts: 3},
+		{name: "gives up after max attempts", policy: fast, statuses: []int{500}, wantStatus: 500, wantAttempts: 3},
+		{name: "400 is not retried", policy: fast, statuses: []int{400, 200}, wantStatus: 400, wantAttempts: 1},
+		{name: "max attempts 1 disables retries", policy: RetryPolicy{MaxAttempts: 1}, statuses: []int{503, 200}, wantStatus: 503, wantAttempts: 1},
+		{
+			name: "Retry-After is honored", policy: hinted, statuses: []int{429, 200},
+			header:     http.Header{"Retry-After": {"0.05"}},
+			wantStatus: 200, wantAttempts: 2, minElapsed: 50 * time.Millisecond,
+		},
+		{
+			name: "retry-after-ms wins over Retry-After", policy: hinted, statuses: []int{529, 200},
+			header:     http.Header{"Retry-After-Ms": {"30"}, "Retry-After": {"60"}},
+			wantStatus: 200, wantAttempts: 2, minElapsed: 30 * time.Millisecond,
+		},
+		{
+			name: "hint too far off without a deadline is not waited for", policy: hinted, statuses: []int{503, 200},
+			header:     http.Header{"Retry-After": {"1"}},
+			wantStatus: 503, wantAttempts: 1,
+		},
+		{
+			name: "hint past the deadline returns the original response", policy: hinted, statuses: []int{429, 200},
+			header: http.Header{"Retry-After": {"10"}}, timeout: time.Second,
+			wantStatus: 429, wantAttempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := scriptedServer(t, tt.statuses, tt.header)
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			start := time.Now()
+			resp, attempts, err := tt.policy.do(ctx, srv.Client(), func() (*http.Request, error) {
+				return http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+			})
+			if err != nil {
+				t.Fatalf("do: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if attempts != tt.wantAttempts || int(calls.Load()) != tt.wantAttempts {
+				t.Errorf("attempts = %d (server saw %d), want %d", attempts, calls.Load(), tt.wantAttempts)
+			}
+			if el := time.Since(start); el < tt.minElapsed {
+				t.Errorf("elapsed %s, want at least %s", el, tt.minElapsed)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDoPermanentStatus(t *testing.T) {
+	fast := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	tests := []struct {
+		name         string
+		body         string
+		wantStatus   int
+		wantAttempts int
+	}{
+		{
+			name:       "insufficient_quota is not retried",
+			body:       `{"error":{"message":"You exceeded your current quota.","type":"insufficient_quota","code":"insufficient_quota"}}`,
+			wantStatus: 429, wantAttempts: 1,
+		},
+		{
+			name:       "a transient rate limit is retried",
+			body:       `{"error":{"message":"Rate limit reached for requests","type":"requests","code":"rate_limit_exceeded"}}`,
+			wantStatus: 200, wantAttempts: 2,
+		},
+		{name: "an empty body is retried", wantStatus: 200, wantAttempts: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) == 1 {
+					w.WriteHeader(http.StatusTooManyRequests)
+					fmt.Fprint(w, tt.body)
+				}
+			}))
+			defer srv.Close()
+			resp, attempts, err := fast.do(context.Background(), srv.Client(), func() (*http.Request, error) {
+				return http.NewRequest(http.MethodPost, srv.URL, nil)
+			})
+			if err != nil {
+				t.Fatalf("do: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus || attempts != tt.wantAttempts || int(calls.Load()) != tt.wantAttempts {
+				t.Fatalf("status %d after %d attempts (server saw %d), want %d after %d", resp.StatusCode, attempts, calls.Load(), tt.wantStatus, tt.wantAttempts)
+			}
+			if tt.wantStatus != http.StatusTooManyRequests {
+				return
+			}
+			// The peeked body is still there for the caller's httpError.
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDoTransportError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	url := srv.URL
+	srv.Close() // connections are refused from now on
+
+	p := RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	resp, attempts, err := p.do(context.Background(), &http.Client{}, func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, url, nil)
+	})
+	if err == nil || resp != nil {
+		t.Fatalf("do = (%v, %v), want a transport error", resp, err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+
+	// A non-retryable *Error from the transport stops at once.
+	hc := &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, &Error{Kind: KindInvalidRequest}
+	})}
+	_, attempts, err = p.do(context.Background(), hc, func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, url, nil)
+	})
+	if !errors.Is(err, ErrInvalidRequest) || attempts != 1 {
+		t.Errorf("do = (%d, %v), want 1 attempt and ErrInvalidRequest", attempts, err)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestServerRetryHint(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{"none", http.Header{}, 0},
+		{"retry-after-ms", http.Header{"Retry-After-Ms": {"1500"}}, 1500 * time.Millisecond},
+		{"retry-after seconds", http.Header{"Retry-After": {"2"}}, 2 * time.Second},
+		{"retry-after date", http.Header{"Retry-After": {now.Add(7 * time.Second).Format(http.TimeFormat)}}, 7 * time.Second},
+		{"retry-after date in the past", http.Header{"Retry-After": {now.Add(-time.Minute).Format(http.TimeFormat)}}, 0},
+		{"garbage", http.Header{"Retry-After": {"soon"}}, 0},
+		{
+			"anthropic exhausted buckets take the latest reset",
+			http.Header{
+				"Anthropic-Ratelimit-Requests-Remaining": {"0"},
+				"Anthropic-Ratelimit-Requests-Reset":     {now.Add(3 * time.Second).Format(time.RFC3339)},
+				"Anthropic-Ratelimit-Tokens-Remaining":   {"0"},
+				"Anthropic-Ratelimit-Tokens-Reset":       {now.Add(9 * time.Second).Format(time.RFC3339)},
+			},
+			9 * time.Second,
+		},
+		{
+			"anthropic bucket with capacity left is ignored",
+			http.Header{
+				"Anthropic-Ratelimit-Requests-Remaining": {"12"},
+				"Anthropic-Ratelimit-Requests-Reset":     {now.Add(3 * time.Second).Format(time.RFC3339)},
+			},
+			0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serverRetryHint(tt.header, now); got != tt.want {
+				t.Errorf("serverRetryHint = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 400 * time.Millisecond}.normalized()
+	for attempt, ceiling := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 400 * time.Millisecond,
+		6: 400 * time.Millisecond, // capped
+	} {
+		for i := 0; i < 50; i++ {
+			if d := p.backoff(attempt); d <= 0 || d > ceiling {
+				t.Fatalf("backoff(%d) = %s, want in (0, %s]", attempt, d, ceiling)
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Sampling holds optional decoding parameters; nil/empty fields are left to
+// the provider's default. Not every API accepts every field, see ValidateSampling.
+type Sampling struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	TopK             *int     `json:"top_k,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	Seed             *int64   `json:"seed,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+}
+
+// set returns the JSON names of the fields that are set.
+func (s Sampling) set() []string {
+	var out []string
+	if s.Temperature != nil {
+		out = append(out, "temperature")
+	}
+	if s.TopP != nil {
+		out = append(out, "top_p")
+	}
+	if s.TopK != nil {
+		out = append(out, "top_k")
+	}
+	if len(s.Stop) > 0 {
+		out = append(out, "stop")
+	}
+	if s.Seed != nil {
+		out = append(out, "seed")
+	}
+	if s.PresencePenalty != nil {
+		out = append(out, "presence_penalty")
+	}
+	if s.FrequencyPenalty != nil {
+		out = append(out, "frequency_penalty")
+	}
+	return out
+}
+
+// ValidateSampling checks s against the provider's Capabilities: unsupported
+// parameters and out-of-range values are errors. Unknown providers are not
+// checked here (client construction reports them).
+func ValidateSampling(provider string, s Sampling) error {
+	caps, ok := capabilities(provider)
+	if !ok {
+		return nil
+	}
+	maxTemp := caps.MaxTemperature
+	if maxTemp == 0 {
+		maxTemp = 2
+	}
+	supported := map[string]bool{}
+	for _, p := range caps.Sampling {
+		supported[p] = true
+	}
+	var bad []string
+	for _, p := range s.set() {
+		if !supported[p] {
+			bad = append(bad, p)
+		}
+	}
+	if len(bad) > 0 {
+		sort.Strings(bad)
+		return fmt.Errorf("provider %q does not support %s", provider, strings.Join(bad, ", "))
+	}
+	if t := s.Temperature; t != nil && (*t < 0 || *t > maxTemp) {
+		return fmt.Errorf("temperature %g out of range [0, %g]", *t, maxTemp)
+	}
+	if p := s.TopP; p != nil && (*p < 0 || *p > 1) {
+		return fmt.Errorf("top_p %g out of range [0, 1]", *p)
+	}
+	if k := s.TopK; k != nil && *k < 1 {
+		return fmt.Errorf("top_k must be at least 1, got %d", *k)
+	}
+	if caps.MaxStop > 0 && len(s.Stop) > caps.MaxStop {
+		return fmt.Errorf("at most %d stop sequences allowed, got %d", caps.MaxStop, len(s.Stop))
+	}
+	for _, pen := range []struct {
+		name string
+		v    *float64
+	}{{"presence_penalty", s.PresencePenalty}, {"frequency_penalty", s.FrequencyPenalty}} {
+		if pen.v != nil && (*pen.v < -2 || *pen.v > 2) {
+			return fmt.Errorf("%s %g out of range [-2, 2]", pen.name, *pen.v)
+		}
+	}
+	return nil
+}
+
+// putChat sets the fields that are set under their snake_case names, as used
+// by Chat Completions bodies and Ollama options.
+func (s Sampling) putChat(m map[string]any) {
+	if s.Temperature != nil {
+		m["temperature"] = *s.Temperature
+	}
+	if s.TopP != nil {
+		m["top_p"] = *s.TopP
+	}
+	if s.TopK != nil {
+		m["top_k"] = *s.TopK
+	}
+	if len(s.Stop) > 0 {
+		m["stop"] = s.Stop
+	}
+	if s.Seed != nil {
+		m["seed"] = *s.Seed
+	}
+	if s.PresencePenalty != nil {
+		m["presence_penalty"] = *s.PresencePenalty
+	}
+	if s.FrequencyPenalty != nil {
+		m["frequency_penalty"] = *s.FrequencyPenalty
+	}
+}
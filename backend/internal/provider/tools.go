@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Tool declares a function the model may call. Parameters is a JSON Schema
+// object; nil means "no arguments".
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ToolCall is a function call requested by the model. Arguments is a JSON object.
+// ID links the call to its result message; providers without call IDs (Gemini)
+// get a synthesized one.
+type ToolCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// ToolResult builds the message that answers call with output.
+func ToolResult(call ToolCall, output string) Message {
+	return Message{Role: RoleTool, Content: output, ToolCallID: call.ID, Name: call.Name}
+}
+
+func (t Tool) schema() json.RawMessage {
+	if len(t.Parameters) == 0 {
+		return json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+	return t.Parameters
+}
+
+// args returns the call arguments as a JSON object, defaulting to {}.
+func (c ToolCall) args() json.RawMessage {
+	if len(c.Arguments) == 0 || string(c.Arguments) == "null" {
+		return json.RawMessage(`{}`)
+	}
+	return c.Arguments
+}
+
+// argsObject decodes the arguments for APIs that take them as an object.
+func (c ToolCall) argsObject() map[string]any {
+	m := map[string]any{}
+	_ = json.Unmarshal(c.args(), &m)
+	return m
+}
+
+// argsFromString parses the string-encoded arguments used by OpenAI-style APIs.
+func argsFromString(s string) json.RawMessage {
+	if strings.TrimSpace(s) == "" {
+		return json.RawMessage(`{}`)
+	}
+	if json.Valid([]byte(s)) {
+		return json.RawMessage(s)
+	}
+	b, _ := json.Marshal(map[string]string{"_raw": s})
+	return b
+}
+
+// toolOutputObject wraps a tool output for APIs that want an object (Gemini):
+// JSON objects pass through, anything else becomes {"result": output}.
+func toolOutputObject(output string) map[string]any {
+	var m map[string]any
+	if json.Unmarshal([]byte(output), &m) == nil && m != nil {
+		return m
+	}
+	return map[string]any{"result": output}
+}
+
+func syntheticCallID(name string, i int) string {
+	return "call_" + name + "_" + strconv.Itoa(i)
+}
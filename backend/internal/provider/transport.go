@@ -0,0 +1,185 @@
+package provider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TransportConfig tunes the HTTP transport shared by provider clients.
+// Zero fields take the DefaultTransportConfig value.
+//
+// Proxy is an http(s):// or socks5:// URL; empty uses HTTP(S)_PROXY from
+// the environment and "direct" disables proxying. CAFile is a PEM bundle
+// trusted in addition to the system roots (corporate proxies, self-hosted
+// endpoints with a private CA).
+type TransportConfig struct {
+	Proxy  string
+	CAFile string
+
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration // 0 = none; the client timeout still applies
+	IdleConnTimeout       time.Duration
+
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int // 0 = unlimited
+
+	DisableHTTP2 bool
+}
+
+// DefaultTransportConfig keeps enough idle connections per host for a full
+// fan-out of runners to the same API.
+var DefaultTransportConfig = TransportConfig{
+	DialTimeout:         10 * time.Second,
+	TLSHandshakeTimeout: 10 * time.Second,
+	IdleConnTimeout:     90 * time.Second,
+	MaxIdleConns:        100,
+	MaxIdleConnsPerHost: 16,
+}
+
+// merge returns c with its zero fields taken from base.
+func (c TransportConfig) merge(base TransportConfig) TransportConfig {
+	if c.Proxy == "" {
+		c.Proxy = base.Proxy
+	}
+	if c.CAFile == "" {
+		c.CAFile = base.CAFile
+	}
+	if c.DialTimeout <= 0 {
+		c.DialTimeout = base.DialTimeout
+	}
+	if c.TLSHandshakeTimeout <= 0 {
+		c.TLSHandshakeTimeout = base.TLSHandshakeTimeout
+	}
+	if c.ResponseHeaderTimeout <= 0 {
+		c.ResponseHeaderTimeout = base.ResponseHeaderTimeout
+	}
+	if c.IdleConnTimeout <= 0 {
+		c.IdleConnTimeout = base.IdleConnTimeout
+	}
+	if c.MaxIdleConns <= 0 {
+		c.MaxIdleConns = base.MaxIdleConns
+	}
+	if c.MaxIdleConnsPerHost <= 0 {
+		c.MaxIdleConnsPerHost = base.MaxIdleConnsPerHost
+	}
+	if c.MaxConnsPerHost <= 0 {
+		c.MaxConnsPerHost = base.MaxConnsPerHost
+	}
+	c.DisableHTTP2 = c.DisableHTTP2 || base.DisableHTTP2
+	return c
+}
+
+// NewTransport builds an *http.Transport from c (merged over the defaults).
+func NewTransport(c TransportConfig) (*http.Transport, error) {
+	c = c.merge(DefaultTransportConfig)
+	t := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: c.DialTimeout, KeepAlive: 30 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   c.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: c.ResponseHeaderTimeout,
+		IdleConnTimeout:       c.IdleConnTimeout,
+		MaxIdleConns:          c.MaxIdleConns,
+		MaxIdleConnsPerHost:   c.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       c.MaxConnsPerHost,
+		ExpectContinueTimeout: time.Second,
+		ForceAttemptHTTP2:     !c.DisableHTTP2,
+	}
+	switch p := strings.TrimSpace(c.Proxy); {
+	case p == "":
+	case strings.EqualFold(p, "direct"):
+		t.Proxy = nil
+	default:
+		u, err := url.Parse(p)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("proxy %q: not a URL", p)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("proxy %q: unsupported scheme %q", p, u.Scheme)
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("ca_file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_file %s: no PEM certificates found", c.CAFile)
+		}
+		t.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	if c.DisableHTTP2 {
+		// A non-nil empty map is how net/http is told not to negotiate h2.
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return t, nil
+}
+
+// Shared transports: one default plus per-provider overrides, built once and
+// reused by every client so connections survive across requests (clients
+// themselves are rebuilt per request).
+var transports = struct {
+	sync.Mutex
+	def         http.RoundTripper
+	perProvider map[string]http.RoundTripper
+}{}
+
+// SetTransports configures the shared transports: def for all providers, with
+// the fields set in perProvider (keyed by provider name or alias) overriding
+// it for that provider. Clients built afterwards use them.
+func SetTransports(def TransportConfig, perProvider map[string]TransportConfig) error {
+	d, err := NewTransport(def)
+	if err != nil {
+		return err
+	}
+	per := make(map[string]http.RoundTripper, len(perProvider))
+	for name, c := range perProvider {
+		spec, ok := Lookup(name)
+		if !ok {
+			return fmt.Errorf("unknown provider %q", name)
+		}
+		t, err := NewTransport(c.merge(def))
+		if err != nil {
+			return fmt.Errorf("%s: %w", spec.Name, err)
+		}
+		per[spec.Name] = t
+	}
+	transports.Lock()
+	defer transports.Unlock()
+	if old, ok := transports.def.(*http.Transport); ok {
+		old.CloseIdleConnections()
+	}
+	transports.def, transports.perProvider = d, per
+	return nil
+}
+
+// transportFor returns the shared transport of a provider, building the
+// default one on first use.
+func transportFor(provider string) http.RoundTripper {
+	transports.Lock()
+	defer transports.Unlock()
+	if t, ok := transports.perProvider[provider]; ok {
+		return t
+	}
+	if transports.def == nil {
+		t, _ := NewTransport(TransportConfig{}) // the defaults cannot fail
+		transports.def = t
+	}
+	return transports.def
+}
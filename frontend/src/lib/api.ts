@@ -4,6 +4,18 @@
  */
 const API_BASE = (import.meta.env.VITE_API_BASE as string | undefined) || ''
 
+// Structured runner failure; `kind` is stable (auth, rate_limit, safety, context_length,
+// invalid_request, timeout, server, network, canceled, empty_output, bad_response, circuit_open, unknown).
+export type RunnerError = {
+  kind: string
+  message: string
+  provider?: string
+  model?: string
+  status?: number
+  code?: string
+  retryable: boolean
+}
+
 export type AskResponse = {
   answer: string
   winner_index: number
@@ -11,6 +23,7 @@ export type AskResponse = {
   scores?: number[]              // judge scores; server should return length == runners
   votes_per_candidate?: number[] // kept for compatibility, but we won't use as fallback
   included_indices?: number[]    // indices that actually produced non-empty answers
+  runner_errors?: (RunnerError | null)[] // index-aligned with runners; null when the runner succeeded
   consensus_id: string
 }
 